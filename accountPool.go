@@ -0,0 +1,207 @@
+package kucoin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Errors returned by AccountPool.
+var (
+	ErrUnknownAccount = errors.New("Unknown account")
+	ErrAccountExists  = errors.New("Account already exists")
+)
+
+// Account configures an account of an AccountPool.
+type Account struct {
+	Name        string
+	Credentials Credentials
+	// RateLimit is the max number of requests per second of the account,
+	// with bursts of Burst requests. 0 means no limit.
+	RateLimit float64
+	Burst     int
+	// Options are applied after the options of the pool.
+	Options []Option
+}
+
+// AccountPool holds the clients of several accounts, e.g. sub-accounts or
+// the API keys of several desks, sharing a market data Cache. Its methods
+// are safe for concurrent use.
+type AccountPool struct {
+	cache *Cache
+	opts  []Option
+
+	mu      sync.RWMutex
+	clients map[string]*Kucoin
+}
+
+// NewAccountPool returns an empty pool whose accounts share cache, a new
+// default one if nil, and the options, e.g. WithMetrics or WithAuditSink.
+func NewAccountPool(cache *Cache, opts ...Option) *AccountPool {
+	if cache == nil {
+		cache = NewCache(CacheConfig{})
+	}
+	return &AccountPool{cache: cache, opts: opts, clients: make(map[string]*Kucoin)}
+}
+
+// Cache returns the cache shared by the accounts.
+func (p *AccountPool) Cache() *Cache {
+	return p.cache
+}
+
+// Add adds an account and returns its client.
+func (p *AccountPool) Add(acc Account) (*Kucoin, error) {
+	if len(acc.Name) < 1 || acc.Credentials == nil {
+		return nil, ErrAllParamsRequired
+	}
+	opts := append([]Option{WithCache(p.cache)}, p.opts...)
+	if acc.RateLimit > 0 {
+		opts = append(opts, WithRateLimit(acc.RateLimit, acc.Burst))
+	}
+	opts = append(opts, acc.Options...)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.clients[acc.Name]; ok {
+		return nil, fmt.Errorf("%s: %w", acc.Name, ErrAccountExists)
+	}
+	k := NewWithCredentials(acc.Credentials, opts...)
+	p.clients[acc.Name] = k
+	return k, nil
+}
+
+// Remove removes an account.
+func (p *AccountPool) Remove(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.clients, name)
+}
+
+// Account returns the client of the named account.
+func (p *AccountPool) Account(name string) (*Kucoin, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	k, ok := p.clients[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", name, ErrUnknownAccount)
+	}
+	return k, nil
+}
+
+// Names returns the names of the accounts, sorted.
+func (p *AccountPool) Names() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	names := make([]string, 0, len(p.clients))
+	for name := range p.clients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// each calls fn concurrently for every account with its client bound to
+// ctx. The errors are returned joined, prefixed by their account name.
+func (p *AccountPool) each(ctx context.Context, fn func(name string, k *Kucoin) error) error {
+	names := p.Names()
+	errs := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		k, err := p.Account(name)
+		if err != nil {
+			// Removed meanwhile.
+			continue
+		}
+		wg.Add(1)
+		go func(i int, name string, k *Kucoin) {
+			defer wg.Done()
+			if err := fn(name, k.WithContext(ctx)); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", name, err)
+			}
+		}(i, name, k)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// PoolBalances are the balances of the accounts of a pool.
+type PoolBalances struct {
+	Totals   map[string]CoinBalance   // per coin, summed over the accounts
+	Accounts map[string][]CoinBalance // per account name
+}
+
+// Balances returns the balances of all accounts. On failures it returns the
+// balances of the other accounts with the errors.
+func (p *AccountPool) Balances(ctx context.Context) (PoolBalances, error) {
+	res := PoolBalances{
+		Totals:   make(map[string]CoinBalance),
+		Accounts: make(map[string][]CoinBalance),
+	}
+	var mu sync.Mutex
+	err := p.each(ctx, func(name string, k *Kucoin) error {
+		balances, err := k.GetAllBalances()
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		res.Accounts[name] = balances
+		for _, b := range balances {
+			total := res.Totals[b.CoinType]
+			total.CoinType = b.CoinType
+			total.Balance += b.Balance
+			total.FreezeBalance += b.FreezeBalance
+			res.Totals[b.CoinType] = total
+		}
+		return nil
+	})
+	return res, err
+}
+
+// ActiveOrders returns the active orders of the symbol of all accounts, per
+// account name. Side may be empty. On failures it returns the orders of the
+// other accounts with the errors.
+func (p *AccountPool) ActiveOrders(ctx context.Context, symbol string, side Side) (map[string]ActiveMapOrder, error) {
+	res := make(map[string]ActiveMapOrder)
+	var mu sync.Mutex
+	err := p.each(ctx, func(name string, k *Kucoin) error {
+		orders, err := k.ListActiveMapOrders(symbol, side)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		res[name] = orders
+		mu.Unlock()
+		return nil
+	})
+	return res, err
+}
+
+// CreateOrder creates an order on the named account.
+func (p *AccountPool) CreateOrder(ctx context.Context, account, symbol string, side Side, price, amount float64) (string, error) {
+	k, err := p.Account(account)
+	if err != nil {
+		return "", err
+	}
+	return k.WithContext(ctx).CreateOrder(symbol, side, price, amount)
+}
+
+// CreateOrderWithOptions creates an order with options on the named account.
+func (p *AccountPool) CreateOrderWithOptions(ctx context.Context, account, symbol string, side Side, price, amount float64, opts OrderOptions) (string, error) {
+	k, err := p.Account(account)
+	if err != nil {
+		return "", err
+	}
+	return k.WithContext(ctx).CreateOrderWithOptions(symbol, side, price, amount, opts)
+}
+
+// CancelOrder cancels an order of the named account.
+func (p *AccountPool) CancelOrder(ctx context.Context, account, symbol, orderOid string, side Side) error {
+	k, err := p.Account(account)
+	if err != nil {
+		return err
+	}
+	return k.WithContext(ctx).CancelOrder(symbol, orderOid, side)
+}
@@ -0,0 +1,170 @@
+package kucoin
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrAuditLogTampered is returned by VerifyAuditLog when the hash chain of
+// an audit log is broken.
+var ErrAuditLogTampered = errors.New("Audit log hash chain is broken")
+
+// AuditRecord records a mutating call: order creation and cancellation,
+// withdrawal apply and cancellation.
+type AuditRecord struct {
+	Time    time.Time         `json:"time"`
+	Action  string            `json:"action"` // name of the method, e.g. CreateOrder
+	Params  map[string]string `json:"params"`
+	Id      string            `json:"id,omitempty"` // order or withdrawal oid
+	Success bool              `json:"success"`
+	Error   string            `json:"error,omitempty"`
+	DryRun  bool              `json:"dryRun,omitempty"`
+}
+
+// AuditSink receives the records of the mutating calls, set with
+// WithAuditSink. Failures are logged, the calls already happened.
+type AuditSink interface {
+	Audit(rec AuditRecord) error
+}
+
+// WithAuditSink makes the client record CreateOrder, CreateOrderByString,
+// CancelOrder, CancelAllOrders, CreateWithdrawalApply and CancelWithdrawal
+// calls into s, whatever their outcome. Orders created by
+// CreateOrderWithOptions are recorded as CreateOrder.
+func WithAuditSink(s AuditSink) Option {
+	return func(c *client) {
+		c.audit = s
+	}
+}
+
+func (k *Kucoin) audit(action string, params map[string]string, id string, err error) {
+	if k.client.audit == nil {
+		return
+	}
+	rec := AuditRecord{
+		Time:    time.Now().UTC(),
+		Action:  action,
+		Params:  params,
+		Id:      id,
+		Success: err == nil,
+		DryRun:  k.client.dryRun,
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	if err = k.client.audit.Audit(rec); err != nil {
+		log.Printf("audit %s: %s\n", action, err)
+	}
+}
+
+// AuditLog is an append-only JSON Lines AuditSink. Each line holds a record
+// with the hash of the previous line, so that edits and removals can be
+// detected with VerifyAuditLog:
+//
+//	{"prev":"<hex>","hash":"<hex>","record":{...}}
+//
+// where hash is the SHA-256 of prev followed by the record JSON.
+type AuditLog struct {
+	mu   sync.Mutex
+	f    *os.File
+	prev string
+}
+
+type auditLine struct {
+	Prev   string          `json:"prev"`
+	Hash   string          `json:"hash"`
+	Record json.RawMessage `json:"record"`
+}
+
+func auditHash(prev string, record []byte) string {
+	h := sha256.New()
+	h.Write([]byte(prev))
+	h.Write(record)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// OpenAuditLog opens the audit log at path, created if needed, and
+// continues its hash chain.
+func OpenAuditLog(path string) (*AuditLog, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	prev, _, err := verifyAuditLog(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &AuditLog{f: f, prev: prev}, nil
+}
+
+// Audit appends the record and syncs the file.
+func (l *AuditLog) Audit(rec AuditRecord) error {
+	record, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	line := auditLine{Prev: l.prev, Hash: auditHash(l.prev, record), Record: record}
+	b, err := json.Marshal(line)
+	if err != nil {
+		return err
+	}
+	if _, err = l.f.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	if err = l.f.Sync(); err != nil {
+		return err
+	}
+	l.prev = line.Hash
+	return nil
+}
+
+// Close closes the file.
+func (l *AuditLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.f.Close()
+}
+
+// VerifyAuditLog checks the hash chain of the audit log at path and returns
+// its number of records.
+func VerifyAuditLog(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	_, n, err := verifyAuditLog(f)
+	return n, err
+}
+
+func verifyAuditLog(f *os.File) (last string, n int, err error) {
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		if len(bytes.TrimSpace(sc.Bytes())) < 1 {
+			continue
+		}
+		var line auditLine
+		if err = json.Unmarshal(sc.Bytes(), &line); err != nil {
+			return last, n, fmt.Errorf("record %d: %w", n+1, err)
+		}
+		if line.Prev != last || auditHash(line.Prev, line.Record) != line.Hash {
+			return last, n, fmt.Errorf("record %d: %w", n+1, ErrAuditLogTampered)
+		}
+		last = line.Hash
+		n++
+	}
+	return last, n, sc.Err()
+}
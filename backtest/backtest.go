@@ -0,0 +1,223 @@
+// Package backtest replays recorded trades and order book updates through
+// the papertrade matching engine and reports how a strategy performed.
+//
+// Strategies receive the same websocket types and the same kucoin.Trader
+// interface they use live, so their code is identical in both modes.
+package backtest
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fiore/kucoin-go"
+	"github.com/fiore/kucoin-go/papertrade"
+	"github.com/fiore/kucoin-go/websocket"
+)
+
+// Strategy is called for every replayed event with the simulated trader.
+type Strategy interface {
+	OnTrade(t kucoin.Trader, h websocket.History)
+	OnOrderBook(t kucoin.Trader, ob websocket.OrderBook)
+}
+
+// Config holds the backtest settings.
+type Config struct {
+	// Symbols holds the meta data of the traded symbols, FeeRate in particular.
+	Symbols []kucoin.Symbol
+	// FeeRate is used for symbols without a known Symbol.FeeRate.
+	FeeRate float64
+	// Balances holds the initial available balance per coin.
+	Balances map[string]float64
+	// Books holds the initial order book per symbol, updated by the replayed deltas.
+	Books map[string]kucoin.OrdersBook
+	// Latency delays the moment an order starts matching.
+	Latency time.Duration
+	// Quote is the coin the equity is valued in, e.g. BTC.
+	Quote string
+}
+
+// Point is a sample of the equity curve.
+type Point struct {
+	Time   time.Time
+	Equity float64
+}
+
+// Stats holds the order and fill statistics of a backtest.
+type Stats struct {
+	Orders         int
+	FilledOrders   int
+	PartialOrders  int
+	UnfilledOrders int
+	Fills          int
+	MakerFills     int
+	TakerFills     int
+	OrderedAmount  float64
+	FilledAmount   float64
+	FillRatio      float64
+	Fees           map[string]float64
+	TradedValue    float64
+}
+
+// Result holds the outcome of a backtest.
+type Result struct {
+	// Equity is the value of all balances in Config.Quote after every event.
+	Equity      []Point
+	PnL         float64
+	MaxDrawdown float64
+	Trades      []kucoin.MergedDeal
+	Balances    []kucoin.CoinBalance
+	Stats       Stats
+}
+
+type placed struct {
+	symbol, orderOid string
+	side             kucoin.Side
+	amount           float64
+}
+
+// trader records the orders placed by the strategy.
+type trader struct {
+	*papertrade.Exchange
+	orders []placed
+}
+
+func (t *trader) CreateOrder(symbol string, side kucoin.Side, price, amount float64) (orderOid string, err error) {
+	orderOid, err = t.Exchange.CreateOrder(symbol, side, price, amount)
+	if err == nil {
+		t.orders = append(t.orders, placed{symbol, orderOid, side, amount})
+	}
+	return
+}
+
+func (t *trader) CreateOrderByString(symbol string, side kucoin.Side, price, amount string) (orderOid string, err error) {
+	if len(symbol) < 1 || len(side) < 1 || len(price) < 1 || len(amount) < 1 {
+		return orderOid, kucoin.ErrAllParamsRequired
+	}
+	p, err := strconv.ParseFloat(price, 64)
+	if err != nil {
+		return
+	}
+	a, err := strconv.ParseFloat(amount, 64)
+	if err != nil {
+		return
+	}
+	return t.CreateOrder(symbol, side, p, a)
+}
+
+// Run replays events in order through a simulated exchange, calling the
+// strategy after each of them.
+func Run(cfg Config, events []Event, strategy Strategy) (res Result, err error) {
+	var now time.Time
+	exchange := papertrade.New(papertrade.Config{
+		Balances: cfg.Balances,
+		FeeRate:  cfg.FeeRate,
+		Latency:  cfg.Latency,
+		Clock:    func() time.Time { return now },
+	})
+	for _, s := range cfg.Symbols {
+		exchange.SetSymbol(s)
+	}
+	for symbol, ordersBook := range cfg.Books {
+		exchange.ApplyBook(symbol, ordersBook)
+	}
+	t := &trader{Exchange: exchange}
+
+	prices := make(map[string]float64)
+	for _, s := range cfg.Symbols {
+		if s.LastDealPrice > 0 {
+			prices[strings.ToUpper(s.Symbol)] = s.LastDealPrice
+		}
+	}
+	quote := strings.ToUpper(cfg.Quote)
+	initial := equity(exchange.Balances(), prices, quote)
+	peak := initial
+
+	for _, e := range events {
+		now = e.Time()
+		switch {
+		case e.History != nil:
+			exchange.ApplyTrade(*e.History)
+			prices[strings.ToUpper(e.History.Symbol)] = e.History.Price
+			strategy.OnTrade(t, *e.History)
+		case e.OrderBook != nil:
+			exchange.ApplyDelta(*e.OrderBook)
+			strategy.OnOrderBook(t, *e.OrderBook)
+		default:
+			continue
+		}
+
+		v := equity(exchange.Balances(), prices, quote)
+		res.Equity = append(res.Equity, Point{now, v})
+		if v > peak {
+			peak = v
+		}
+		if peak > 0 && (peak-v)/peak > res.MaxDrawdown {
+			res.MaxDrawdown = (peak - v) / peak
+		}
+	}
+
+	res.Trades = exchange.Deals()
+	res.Balances = exchange.Balances()
+	if len(res.Equity) > 0 {
+		res.PnL = res.Equity[len(res.Equity)-1].Equity - initial
+	}
+	res.Stats, err = stats(t, res.Trades)
+	return
+}
+
+// equity values balances in quote with the last traded prices.
+// Coins without a direct market to quote are left out.
+func equity(balances []kucoin.CoinBalance, prices map[string]float64, quote string) (v float64) {
+	for _, b := range balances {
+		amount := b.Balance + b.FreezeBalance
+		switch {
+		case b.CoinType == quote:
+			v += amount
+		case prices[b.CoinType+"-"+quote] > 0:
+			v += amount * prices[b.CoinType+"-"+quote]
+		case prices[quote+"-"+b.CoinType] > 0:
+			v += amount / prices[quote+"-"+b.CoinType]
+		}
+	}
+	return
+}
+
+func stats(t *trader, trades []kucoin.MergedDeal) (s Stats, err error) {
+	s.Fees = make(map[string]float64)
+	for _, o := range t.orders {
+		var details kucoin.OrderDetails
+		if details, err = t.Exchange.OrderDetails(o.symbol, o.side, o.orderOid, 0, 0); err != nil {
+			return
+		}
+		s.Orders++
+		s.OrderedAmount += o.amount
+		s.FilledAmount += details.DealAmount
+		switch {
+		case details.DealAmount >= o.amount-1e-12:
+			s.FilledOrders++
+		case details.DealAmount > 0:
+			s.PartialOrders++
+		default:
+			s.UnfilledOrders++
+		}
+	}
+	for _, d := range trades {
+		s.Fills++
+		if d.DealDirection == d.Direction {
+			s.TakerFills++
+		} else {
+			s.MakerFills++
+		}
+		s.TradedValue += d.DealValue
+		feeCoin := d.CoinType
+		if d.Direction == kucoin.Sell {
+			feeCoin = d.CoinTypePair
+		}
+		s.Fees[feeCoin] += d.Fee
+	}
+	if s.OrderedAmount > 0 {
+		s.FillRatio = s.FilledAmount / s.OrderedAmount
+	}
+	return
+}
@@ -0,0 +1,60 @@
+package backtest_test
+
+import (
+	"bytes"
+	"testing"
+
+	kucoinGo "github.com/fiore/kucoin-go"
+	"github.com/fiore/kucoin-go/backtest"
+	"github.com/fiore/kucoin-go/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+// buyOnce buys on the first trade and does nothing afterwards.
+type buyOnce struct {
+	done bool
+	err  error
+}
+
+func (s *buyOnce) OnTrade(t kucoinGo.Trader, h websocket.History) {
+	if !s.done {
+		s.done = true
+		_, s.err = t.CreateOrder(h.Symbol, kucoinGo.Buy, h.Price, 10)
+	}
+}
+
+func (s *buyOnce) OnOrderBook(t kucoinGo.Trader, ob websocket.OrderBook) {}
+
+func TestRun(t *testing.T) {
+	var buf bytes.Buffer
+	err := backtest.WriteEvents(&buf, []backtest.Event{
+		{Symbol: "KCS-BTC", OrderBook: &websocket.OrderBook{Price: 0.001, Count: 10, Action: "ADD", Type: kucoinGo.Sell, Time: kucoinGo.MillisOf(1000)}},
+		{Symbol: "KCS-BTC", History: &websocket.History{Price: 0.001, Count: 1, Direction: kucoinGo.Buy, Time: kucoinGo.MillisOf(2000)}},
+		{Symbol: "KCS-BTC", History: &websocket.History{Price: 0.0008, Count: 1, Direction: kucoinGo.Sell, Time: kucoinGo.MillisOf(3000)}},
+		{Symbol: "KCS-BTC", History: &websocket.History{Price: 0.0012, Count: 1, Direction: kucoinGo.Buy, Time: kucoinGo.MillisOf(4000)}},
+	})
+	require.NoError(t, err)
+	events, err := backtest.ReadEvents(&buf)
+	require.NoError(t, err)
+	require.Len(t, events, 4)
+	require.Equal(t, "KCS-BTC", events[1].History.Symbol)
+
+	strategy := &buyOnce{}
+	res, err := backtest.Run(backtest.Config{
+		Symbols:  []kucoinGo.Symbol{{Symbol: "KCS-BTC", CoinType: "KCS", CoinTypePair: "BTC", FeeRate: 0.001}},
+		Balances: map[string]float64{"BTC": 1},
+		Quote:    "BTC",
+	}, events, strategy)
+	require.NoError(t, err)
+	require.NoError(t, strategy.err)
+
+	require.Len(t, res.Trades, 1)
+	require.Len(t, res.Equity, 4)
+	// Bought 9.99 KCS (net of fee) for 0.01 BTC, valued at 0.0012 in the end.
+	require.InDelta(t, 9.99*0.0012-0.01, res.PnL, 1e-9)
+	require.InDelta(t, 1-(0.99+9.99*0.0008), res.MaxDrawdown, 1e-9)
+	require.Equal(t, 1, res.Stats.Orders)
+	require.Equal(t, 1, res.Stats.FilledOrders)
+	require.Equal(t, 1, res.Stats.TakerFills)
+	require.InDelta(t, 0.01, res.Stats.Fees["KCS"], 1e-9)
+}
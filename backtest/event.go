@@ -0,0 +1,88 @@
+package backtest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/fiore/kucoin-go/websocket"
+)
+
+// Event is a recorded market data event: either a trade from the THistory
+// topic or an order book update from the TOrderBook topic.
+// Recorded files hold one JSON encoded Event per line.
+type Event struct {
+	Symbol    string               `json:"symbol"`
+	History   *websocket.History   `json:"history,omitempty"`
+	OrderBook *websocket.OrderBook `json:"orderBook,omitempty"`
+}
+
+// Time returns the event timestamp.
+func (e Event) Time() time.Time {
+	switch {
+	case e.History != nil:
+		return e.History.Time.Time
+	case e.OrderBook != nil:
+		return e.OrderBook.Time.Time
+	}
+	return time.Time{}
+}
+
+// ReadEvents reads JSON Lines encoded events from r.
+func ReadEvents(r io.Reader) (events []Event, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Event
+		if err = json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+		if e.History != nil {
+			e.History.Symbol = e.Symbol
+		}
+		if e.OrderBook != nil {
+			e.OrderBook.Symbol = e.Symbol
+		}
+		events = append(events, e)
+	}
+	return events, scanner.Err()
+}
+
+// LoadFiles reads the events of all files and merges them in time order.
+func LoadFiles(paths ...string) (events []Event, err error) {
+	for _, path := range paths {
+		var f *os.File
+		if f, err = os.Open(path); err != nil {
+			return nil, err
+		}
+		var fileEvents []Event
+		fileEvents, err = ReadEvents(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+		events = append(events, fileEvents...)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time().Before(events[j].Time())
+	})
+	return events, nil
+}
+
+// WriteEvents writes events to w as JSON Lines.
+func WriteEvents(w io.Writer, events []Event) error {
+	enc := json.NewEncoder(w)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
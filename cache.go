@@ -0,0 +1,199 @@
+package kucoin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCacheTTL is the expiry per endpoint of the responses cached by
+// NewCache: coins (GetCoins), coin info (GetCoin), symbols (GetSymbols) and
+// open markets (GetOpenMarkets).
+var DefaultCacheTTL = map[string]time.Duration{
+	"market/open/coins":     time.Hour,
+	"market/open/coin-info": time.Hour,
+	"market/open/symbols":   time.Minute,
+	"open/markets":          time.Hour,
+}
+
+// CacheConfig configures a Cache.
+type CacheConfig struct {
+	// TTL is the expiry per endpoint, DefaultCacheTTL if nil. Only the
+	// unsigned GET requests of these endpoints are cached.
+	TTL map[string]time.Duration
+	// Stale is how long an expired response is still returned while it is
+	// refreshed in the background. 0 means expired responses are refetched.
+	Stale time.Duration
+}
+
+// Cache caches responses of slow-changing public endpoints. Concurrent
+// identical requests are sent once. A Cache can be shared by several
+// clients, set with WithCache.
+type Cache struct {
+	cfg     CacheConfig
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	endpoint   string
+	resp       *Response
+	expires    time.Time
+	call       *cacheCall // in-flight request, if any
+	refreshing bool
+}
+
+type cacheCall struct {
+	done chan struct{}
+	resp *Response
+	err  error
+}
+
+// NewCache returns an empty cache.
+func NewCache(cfg CacheConfig) *Cache {
+	if cfg.TTL == nil {
+		cfg.TTL = DefaultCacheTTL
+	}
+	return &Cache{cfg: cfg, entries: make(map[string]*cacheEntry)}
+}
+
+// WithCache makes the client cache responses in c.
+func WithCache(c *Cache) Option {
+	return func(cl *client) {
+		cl.cache = c
+	}
+}
+
+// Invalidate drops the cached responses of the endpoints, e.g.
+// market/open/coins, or all of them if none is given.
+func (c *Cache) Invalidate(endpoints ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, e := range c.entries {
+		// The response of a request in flight is stored in the dropped entry.
+		if len(endpoints) < 1 || contains(endpoints, e.endpoint) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func cacheKey(req *Request) string {
+	keys := make([]string, 0, len(req.Payload))
+	for key := range req.Payload {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString(req.Resource)
+	for _, key := range keys {
+		b.WriteString("&" + key + "=" + req.Payload[key])
+	}
+	return b.String()
+}
+
+// cacheable returns if a response can be cached: it is a success.
+func cacheable(resp *Response, err error) bool {
+	if err != nil || resp == nil || resp.Status != 200 {
+		return false
+	}
+	var res struct {
+		Success bool `json:"success"`
+	}
+	return json.Unmarshal(resp.Body, &res) == nil && res.Success
+}
+
+func (c *client) cacheLink(next Handler) Handler {
+	return func(req *Request) (*Response, error) {
+		if c.cache == nil || req.Method != "GET" || req.Auth {
+			return next(req)
+		}
+		ttl := c.cache.cfg.TTL[req.Endpoint()]
+		if ttl <= 0 {
+			return next(req)
+		}
+		return c.cache.get(req, ttl, next)
+	}
+}
+
+func (c *Cache) get(req *Request, ttl time.Duration, next Handler) (*Response, error) {
+	key := cacheKey(req)
+	now := time.Now()
+	c.mu.Lock()
+	e := c.entries[key]
+	if e == nil {
+		e = &cacheEntry{endpoint: req.Endpoint()}
+		c.entries[key] = e
+	}
+	if e.resp != nil && now.Before(e.expires) {
+		resp := *e.resp
+		c.mu.Unlock()
+		return &resp, nil
+	}
+	if e.resp != nil && now.Before(e.expires.Add(c.cfg.Stale)) {
+		if !e.refreshing && e.call == nil {
+			e.refreshing = true
+			r := *req
+			r.Context = context.WithoutCancel(req.Context)
+			r.Header = req.Header.Clone()
+			go func() {
+				c.fetch(e, &r, ttl, next)
+				c.mu.Lock()
+				e.refreshing = false
+				c.mu.Unlock()
+			}()
+		}
+		resp := *e.resp
+		c.mu.Unlock()
+		return &resp, nil
+	}
+	c.mu.Unlock()
+	return c.fetch(e, req, ttl, next)
+}
+
+// fetch sends the request, or waits for the identical one in flight, and
+// caches its response. Waiters don't share the error of a request whose
+// context was done: they send the request again instead.
+func (c *Cache) fetch(e *cacheEntry, req *Request, ttl time.Duration, next Handler) (*Response, error) {
+	c.mu.Lock()
+	for e.call != nil {
+		call := e.call
+		c.mu.Unlock()
+		select {
+		case <-call.done:
+		case <-req.Context.Done():
+			return nil, req.Context.Err()
+		}
+		if !errors.Is(call.err, context.Canceled) && !errors.Is(call.err, context.DeadlineExceeded) {
+			return call.resp, call.err
+		}
+		c.mu.Lock()
+	}
+	call := &cacheCall{done: make(chan struct{})}
+	e.call = call
+	c.mu.Unlock()
+
+	call.resp, call.err = next(req)
+
+	c.mu.Lock()
+	e.call = nil
+	if cacheable(call.resp, call.err) {
+		resp := *call.resp
+		e.resp, e.expires = &resp, time.Now().Add(ttl)
+	}
+	c.mu.Unlock()
+	close(call.done)
+	return call.resp, call.err
+}
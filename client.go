@@ -1,6 +1,7 @@
 package kucoin
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	b64 "encoding/base64"
@@ -11,27 +12,37 @@ import (
 	"log"
 	"net/http"
 	"net/http/httputil"
-	"net/url"
-	"path"
-	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 type client struct {
-	apiKey     string
-	apiSecret  string
+	creds      Credentials
 	httpClient http.Client
 	debug      bool
+	dryRun     bool
+	allowlist  map[string]map[string]struct{}
+	metrics    Metrics
+	tracing    trace.TracerProvider
+	retry      retryPolicy
+	limiter    *rateLimiter
+	cache      *Cache
+	audit      AuditSink
+	mws        []Middleware
+	handler    Handler
 }
 
-func newClient(apiKey, apiSecret string) (c *client) {
+func newClient(creds Credentials, opts []Option) (c *client) {
 	c = &client{
-		apiKey,
-		apiSecret,
-		http.Client{},
-		true,
+		creds:      creds,
+		httpClient: http.Client{},
 	}
 	c.httpClient.Timeout = time.Second * 30
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.handler = c.chain()
 	return
 }
 
@@ -72,73 +83,69 @@ func (c client) dumpResponse(r *http.Response) {
 		  then combine them with & (don't urlencode them, don't add ?, don't add extra &),
 		  e.g. amount=10&price=1.1&type=BUY
 */
-func (c *client) do(method, resource string, payload map[string]string, authNeeded bool) ([]byte, error) {
-	var req *http.Request
-
-	URL, err := url.Parse(kucoinURL)
-	if err != nil {
+func (c *client) do(ctx context.Context, method, resource string, payload map[string]string, authNeeded bool) ([]byte, error) {
+	resp, err := c.handler(&Request{
+		Context:  ctx,
+		Method:   method,
+		Resource: resource,
+		Payload:  payload,
+		Auth:     authNeeded,
+		Header:   make(http.Header),
+	})
+	if resp == nil {
 		return nil, err
 	}
-	URL.Path = path.Join(URL.Path, resource)
-	queryString := ""
-	if method == "GET" {
-		q := URL.Query()
-		for key, value := range payload {
-			q.Set(key, value)
-		}
-		URL.RawQuery = q.Encode()
-		req, err = http.NewRequest("GET", URL.String(), nil)
-		queryString = URL.Query().Encode()
-	} else {
-		postValues := url.Values{}
-		for key, value := range payload {
-			postValues.Set(key, value)
-		}
-		queryString = postValues.Encode()
-		req, err = http.NewRequest(
-			method, URL.String(), strings.NewReader(
-				queryString,
-			),
-		)
-	}
+	return resp.Body, err
+}
+
+// send is the last link of the chain: it sends the request to Kucoin.
+func (c *client) send(req *Request) (*Response, error) {
+	r, err := req.HTTPRequest()
 	if err != nil {
 		return nil, err
 	}
-	if method == "POST" || method == "PUT" {
-		req.Header.Add("Content-Type", "application/x-www-form-urlencoded;charset=utf-8")
-	}
-	req.Header.Add("Accept", "application/json")
-
-	// Auth
-	if authNeeded {
-		if len(c.apiKey) == 0 || len(c.apiSecret) == 0 {
-			return nil, errors.New("API Key and API Secret must be set")
-		}
-
-		nonce := time.Now().UnixNano() / int64(time.Millisecond)
-		req.Header.Add("KC-API-KEY", c.apiKey)
-		req.Header.Add("KC-API-NONCE", fmt.Sprintf("%v", nonce))
-		req.Header.Add(
-			"KC-API-SIGNATURE", c.sign(
-				URL.Path, queryString, nonce,
-			),
-		)
-	}
-
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.httpClient.Do(r)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		c.authFailure(req.Resource)
+	}
 
 	data, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
+	res := &Response{Status: resp.StatusCode, Header: resp.Header, Body: data}
 	if resp.StatusCode != 200 {
 		err = errors.New(resp.Status)
 	}
-	return data, err
+	return res, err
+}
+
+func (c *client) authFailure(resource string) {
+	if c.metrics != nil {
+		c.metrics.AuthFailure(endpointOf(resource))
+	}
+}
+
+// dryRunResponse logs the request which would have been sent in debug mode,
+// its KC-API-* headers redacted, and returns a synthetic successful response
+// in place of Kucoin's one.
+func (c *client) dryRunResponse(r *http.Request) ([]byte, error) {
+	if c.debug {
+		redactHeader(r.Header)
+		dump, err := httputil.DumpRequest(r, true)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("dry run, request not sent: %s\n", dump)
+	}
+	return []byte(fmt.Sprintf(
+		`{"success":true,"code":"OK","msg":"Dry run","timestamp":%d,"data":{"orderOid":"dry-run-%[2]d","txOid":"dry-run-%[2]d"}}`,
+		time.Now().UnixNano()/int64(time.Millisecond), time.Now().UnixNano(),
+	)), nil
 }
 
 func computeHmac256(message, secret string) string {
@@ -148,9 +155,9 @@ func computeHmac256(message, secret string) string {
 	return fmt.Sprintf("%x", h.Sum(nil))
 }
 
-func (c *client) sign(path, queryString string, nonce int64) (signature string) {
+func (c *client) sign(apiSecret, path, queryString string, nonce int64) (signature string) {
 	strForSign := fmt.Sprintf("%s/%v/%s", path, nonce, queryString)
 	signatureStr := b64.StdEncoding.EncodeToString([]byte(strForSign))
-	signature = computeHmac256(signatureStr, c.apiSecret)
+	signature = computeHmac256(signatureStr, apiSecret)
 	return
 }
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/fiore/kucoin-go"
+)
+
+func runBalance(e *env, args []string) error {
+	fs := flag.NewFlagSet("balance", flag.ContinueOnError)
+	all := fs.Bool("all", false, "list zero balances too")
+	if err := parseFlags(fs, args, 0, 1); err != nil {
+		return err
+	}
+	var balances []kucoin.CoinBalance
+	if fs.NArg() > 0 {
+		b, err := e.k.GetCoinBalance(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		balances = append(balances, b)
+	} else {
+		var err error
+		if balances, err = e.k.GetAllBalances(); err != nil {
+			return err
+		}
+	}
+	var res []kucoin.CoinBalance
+	var rows [][]string
+	for _, b := range balances {
+		if !*all && fs.NArg() == 0 && b.Balance == 0 && b.FreezeBalance == 0 {
+			continue
+		}
+		res = append(res, b)
+		rows = append(rows, []string{b.CoinType, formatFloat(b.Balance), formatFloat(b.FreezeBalance)})
+	}
+	return e.out.print(res, []string{"COIN", "AVAILABLE", "FROZEN"}, rows)
+}
+
+func runDeals(e *env, args []string) error {
+	fs := flag.NewFlagSet("deals", flag.ContinueOnError)
+	symbol := fs.String("symbol", "", "only list the deals of the symbol")
+	side := fs.String("side", "", "only list the deals of the side, BUY or SELL")
+	since := fs.String("since", "", "only list the deals since the RFC 3339 time, date or duration ago")
+	limit := fs.Int("limit", 50, "max number of deals")
+	if err := parseFlags(fs, args, 0, 0); err != nil {
+		return err
+	}
+	from, err := parseTime(*since)
+	if err != nil {
+		return err
+	}
+	var deals []kucoin.MergedDeal
+	var rows [][]string
+	it := e.k.IterMergedDealtOrders(*symbol, kucoin.Side(*side), from, time.Time{})
+	for len(deals) < *limit && it.Next(context.Background()) {
+		d := it.Item()
+		deals = append(deals, d)
+		rows = append(rows, []string{
+			formatTime(d.CreatedAt.Time),
+			d.CoinType + "-" + d.CoinTypePair,
+			string(d.Direction),
+			formatFloat(d.DealPrice),
+			formatFloat(d.Amount),
+			formatFloat(d.DealValue),
+			formatFloat(d.Fee),
+			d.OrderOid,
+		})
+	}
+	if err = it.Err(); err != nil {
+		return err
+	}
+	return e.out.print(deals, []string{"TIME", "SYMBOL", "SIDE", "PRICE", "AMOUNT", "VALUE", "FEE", "ORDER"}, rows)
+}
+
+func runHistory(e *env, args []string) error {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	side := fs.String("type", string(kucoin.Deposit), "DEPOSIT or WITHDRAW")
+	status := fs.String("status", string(kucoin.Finished), "FINISHED, CANCEL or PENDING")
+	page := fs.Int("page", 1, "page number")
+	if err := parseFlags(fs, args, 1, 1); err != nil {
+		return err
+	}
+	history, err := e.k.AccountHistory(fs.Arg(0), kucoin.HistoryType(*side), kucoin.WalletStatus(*status), *page)
+	if err != nil {
+		return err
+	}
+	var rows [][]string
+	for _, r := range history.Datas {
+		txId := ""
+		if r.OuterWalletTxid != nil {
+			txId = fmt.Sprint(r.OuterWalletTxid)
+		}
+		rows = append(rows, []string{
+			formatTime(r.CreatedAt.Time),
+			string(r.Type),
+			string(r.Status),
+			formatFloat(r.Amount),
+			formatFloat(r.Fee),
+			r.Address,
+			txId,
+			r.Oid,
+		})
+	}
+	return e.out.print(history, []string{"TIME", "TYPE", "STATUS", "AMOUNT", "FEE", "ADDRESS", "TX", "OID"}, rows)
+}
+
+func runWithdraw(e *env, args []string) error {
+	fs := flag.NewFlagSet("withdraw", flag.ContinueOnError)
+	yes := fs.Bool("yes", false, "create the withdrawal, otherwise only check it")
+	if err := parseFlags(fs, args, 3, 3); err != nil {
+		return err
+	}
+	amount, err := strconv.ParseFloat(fs.Arg(2), 64)
+	if err != nil {
+		return err
+	}
+	var withdrawal kucoin.Withdrawal
+	if *yes {
+		withdrawal, err = e.k.CreateWithdrawalApply(fs.Arg(0), fs.Arg(1), amount)
+	} else {
+		withdrawal, err = e.k.PreflightWithdrawal(fs.Arg(0), fs.Arg(1), amount)
+	}
+	if err != nil {
+		return err
+	}
+	txOid := withdrawal.TxOid
+	if !*yes {
+		txOid = "not sent, pass -yes to create it"
+	}
+	return e.out.print(withdrawal, []string{"COIN", "ADDRESS", "AMOUNT", "FEE", "NET AMOUNT", "TX OID"}, [][]string{{
+		withdrawal.Coin,
+		withdrawal.Address,
+		formatFloat(withdrawal.Amount),
+		formatFloat(withdrawal.Fee),
+		formatFloat(withdrawal.NetAmount),
+		txOid,
+	}})
+}
+
+func runDepositAddress(e *env, args []string) error {
+	fs := flag.NewFlagSet("deposit-address", flag.ContinueOnError)
+	if err := parseFlags(fs, args, 1, 1); err != nil {
+		return err
+	}
+	a, err := e.k.GetCoinDepositAddress(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return e.out.print(a, []string{"COIN", "ADDRESS", "LAST RECEIVED"}, [][]string{{
+		a.CoinType,
+		a.Address,
+		formatTime(a.LastReceivedAt.Time),
+	}})
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/fiore/kucoin-go"
+)
+
+// envKeystorePassphrase holds the passphrase of the -keystore file.
+const envKeystorePassphrase = "KUCOIN_KEYSTORE_PASSPHRASE"
+
+// loadCredentials returns the credentials of the keystore if given, else of
+// the environment if set, else of the config file, which must only be
+// accessible by its owner. A missing default config file is not an error:
+// public commands don't need credentials.
+func loadCredentials(path, keystore string) (kucoin.Credentials, error) {
+	if len(keystore) > 0 {
+		return kucoin.KeystoreCredentials(keystore, os.Getenv(envKeystorePassphrase))
+	}
+	env := kucoin.EnvCredentials("", "")
+	if len(os.Getenv(kucoin.EnvAPIKey)) > 0 || len(os.Getenv(kucoin.EnvAPISecret)) > 0 {
+		return env, nil
+	}
+	explicit := len(path) > 0
+	if !explicit {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return env, nil
+		}
+		path = filepath.Join(home, ".kucoin.json")
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) && !explicit {
+		return env, nil
+	}
+	creds := kucoin.FileCredentials(path)
+	// Fail early on unreadable files or a too permissive mode.
+	if _, _, err := creds.Credentials(); err != nil {
+		return nil, err
+	}
+	return creds, nil
+}
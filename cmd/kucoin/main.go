@@ -0,0 +1,131 @@
+// Command kucoin is a command-line client of the Kucoin API for market data,
+// balances, orders and wallet operations.
+//
+// Usage:
+//
+//	kucoin [flags] <command> [command flags] [arguments]
+//
+// Credentials are read from the keystore given with -keystore, decrypted
+// with the passphrase of the KUCOIN_KEYSTORE_PASSPHRASE environment variable,
+// or from the KUCOIN_API_KEY and KUCOIN_API_SECRET environment variables, or
+// from the JSON config file given with -config (default $HOME/.kucoin.json),
+// which must only be accessible by its owner (e.g. mode 0600):
+//
+//	{"apiKey": "...", "apiSecret": "..."}
+//
+// Run "kucoin help" for the list of commands.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/fiore/kucoin-go"
+)
+
+// errUsage is returned by commands called with wrong arguments.
+var errUsage = errors.New("wrong arguments")
+
+// env holds what commands need: the client and the output printer.
+type env struct {
+	k      *kucoin.Kucoin
+	out    *printer
+	dryRun bool
+	// interrupt replaces SIGINT and SIGTERM when set.
+	interrupt <-chan os.Signal
+}
+
+type command struct {
+	usage string
+	help  string
+	run   func(e *env, args []string) error
+}
+
+var commands = map[string]command{
+	"ticker":          {"ticker SYMBOL", "Show the ticker of a symbol", runTicker},
+	"book":            {"book [-limit N] [-group N] SYMBOL", "Show the order book of a symbol", runBook},
+	"symbols":         {"symbols [-market COIN]", "List the symbols, optionally of a market", runSymbols},
+	"coins":           {"coins", "List the coins with their withdrawal settings", runCoins},
+	"balance":         {"balance [-all] [COIN]", "Show the balance of a coin or the non-zero balances", runBalance},
+	"orders":          {"orders list|create|cancel|cancel-all ...", "List, create and cancel orders", runOrders},
+	"deals":           {"deals [-symbol SYMBOL] [-side SIDE] [-since TIME] [-limit N]", "List the dealt orders", runDeals},
+	"history":         {"history [-type DEPOSIT|WITHDRAW] [-status FINISHED|CANCEL|PENDING] [-page N] COIN", "List deposits and withdrawals", runHistory},
+	"withdraw":        {"withdraw [-yes] COIN ADDRESS AMOUNT", "Check and create a withdrawal", runWithdraw},
+	"deposit-address": {"deposit-address COIN", "Show the deposit address of a coin", runDepositAddress},
+	"stream":          {"stream [-topics book,history,tick,market] [-format text|ndjson] [-side SIDE] [-min-size N] [-tee FILE] SYMBOL...", "Print live websocket updates", runStream},
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: kucoin [flags] <command> [command flags] [arguments]")
+	fmt.Fprintln(w, "\nFlags:")
+	flag.CommandLine.SetOutput(w)
+	flag.PrintDefaults()
+	fmt.Fprintln(w, "\nCommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-16s %s\n      kucoin %s\n", name, commands[name].help, commands[name].usage)
+	}
+}
+
+func main() {
+	configPath := flag.String("config", "", "JSON config file with apiKey and apiSecret (default $HOME/.kucoin.json)")
+	keystorePath := flag.String("keystore", "", "encrypted keystore with the credentials, see kucoin.WriteKeystore")
+	jsonOut := flag.Bool("json", false, "print JSON instead of tables")
+	dryRun := flag.Bool("dry-run", false, "validate and sign orders and withdrawals without sending them, logged with -debug")
+	debug := flag.Bool("debug", false, "dump HTTP requests and responses")
+	flag.Usage = func() { usage(os.Stderr) }
+	flag.Parse()
+
+	if flag.NArg() < 1 || flag.Arg(0) == "help" {
+		usage(os.Stdout)
+		return
+	}
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "kucoin: unknown command %q\n", flag.Arg(0))
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+
+	creds, err := loadCredentials(*configPath, *keystorePath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "kucoin:", err)
+		os.Exit(1)
+	}
+	var opts []kucoin.Option
+	if *dryRun {
+		opts = append(opts, kucoin.WithDryRun())
+	}
+	k := kucoin.NewWithCredentials(creds, opts...)
+	k.SetDebug(*debug)
+
+	e := &env{k: k, out: &printer{w: os.Stdout, json: *jsonOut}, dryRun: *dryRun}
+	if err = cmd.run(e, flag.Args()[1:]); err != nil {
+		if err == errUsage {
+			fmt.Fprintf(os.Stderr, "Usage: kucoin %s\n", cmd.usage)
+			os.Exit(2)
+		}
+		fmt.Fprintln(os.Stderr, "kucoin:", err)
+		os.Exit(1)
+	}
+}
+
+// parseFlags parses the command flags and checks the number of arguments.
+func parseFlags(fs *flag.FlagSet, args []string, min, max int) error {
+	fs.SetOutput(io.Discard)
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() < min || max >= 0 && fs.NArg() > max {
+		return errUsage
+	}
+	return nil
+}
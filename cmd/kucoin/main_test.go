@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	kucoinGo "github.com/fiore/kucoin-go"
+	"github.com/fiore/kucoin-go/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadCredentials(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "kucoin.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"apiKey":"file-key","apiSecret":"file-secret"}`), 0600))
+	check := func(creds kucoinGo.Credentials, key, secret string) {
+		t.Helper()
+		apiKey, apiSecret, err := creds.Credentials()
+		require.NoError(t, err)
+		require.Equal(t, []string{key, secret}, []string{apiKey, apiSecret})
+	}
+
+	t.Setenv(kucoinGo.EnvAPIKey, "")
+	t.Setenv(kucoinGo.EnvAPISecret, "")
+	creds, err := loadCredentials(path, "")
+	require.NoError(t, err)
+	check(creds, "file-key", "file-secret")
+
+	// The environment overrides the config file.
+	t.Setenv(kucoinGo.EnvAPIKey, "env-key")
+	t.Setenv(kucoinGo.EnvAPISecret, "env-secret")
+	creds, err = loadCredentials(path, "")
+	require.NoError(t, err)
+	check(creds, "env-key", "env-secret")
+
+	// The keystore overrides both.
+	keystore := filepath.Join(dir, "kucoin.keystore")
+	require.NoError(t, kucoinGo.WriteKeystore(keystore, "passphrase", "keystore-key", "keystore-secret"))
+	t.Setenv(envKeystorePassphrase, "passphrase")
+	creds, err = loadCredentials(path, keystore)
+	require.NoError(t, err)
+	check(creds, "keystore-key", "keystore-secret")
+
+	t.Setenv(kucoinGo.EnvAPIKey, "")
+	t.Setenv(kucoinGo.EnvAPISecret, "")
+	require.NoError(t, os.Chmod(path, 0644))
+	_, err = loadCredentials(path, "")
+	require.ErrorIs(t, err, kucoinGo.ErrCredentialsFileMode)
+
+	_, err = loadCredentials(filepath.Join(dir, "missing.json"), "")
+	require.Error(t, err)
+}
+
+func TestParseTime(t *testing.T) {
+	at, err := parseTime("2018-01-02T03:04:05Z")
+	require.NoError(t, err)
+	require.Equal(t, int64(1514862245), at.Unix())
+
+	at, err = parseTime("")
+	require.NoError(t, err)
+	require.True(t, at.IsZero())
+
+	_, err = parseTime("TEST")
+	require.Error(t, err)
+}
+
+func TestStreamFilter(t *testing.T) {
+	filter := streamFilter{side: kucoinGo.Buy, minSize: 10}
+	require.True(t, filter.keep(&websocket.History{Direction: kucoinGo.Buy, Count: 10}))
+	require.False(t, filter.keep(&websocket.History{Direction: kucoinGo.Buy, Count: 9}))
+	require.False(t, filter.keep(&websocket.OrderBook{Type: kucoinGo.Sell, Count: 10}))
+	require.True(t, filter.keep(&websocket.Market{}))
+
+	var buf bytes.Buffer
+	require.NoError(t, printUpdate(&buf, update{websocket.THistory, "KCS-BTC", &websocket.History{
+		Direction: kucoinGo.Sell, Price: 0.00017, Count: 12.5,
+	}}))
+	require.Contains(t, buf.String(), "KCS-BTC    TRADE SELL 0.00017 x 12.5")
+}
+
+func TestStreamForward(t *testing.T) {
+	history, book := make(chan interface{}, 1), make(chan interface{}, 2)
+	updates := forward([]subscription{
+		{websocket.THistory, "KCS-BTC", history},
+		{websocket.TOrderBook, "KCS-BTC", book},
+	}, make(chan struct{}))
+
+	book <- nil
+	book <- &websocket.OrderBook{Count: 1}
+	close(book)
+	u := <-updates
+	require.Equal(t, websocket.TOrderBook, u.Topic)
+	require.Equal(t, &websocket.OrderBook{Count: 1}, u.Data)
+
+	// The updates are closed once all subscriptions are.
+	history <- &websocket.History{Count: 2}
+	close(history)
+	u = <-updates
+	require.Equal(t, websocket.THistory, u.Topic)
+	_, ok := <-updates
+	require.False(t, ok)
+
+	// Or once done is.
+	done := make(chan struct{})
+	updates = forward([]subscription{{websocket.THistory, "KCS-BTC", make(chan interface{})}}, done)
+	close(done)
+	ok = true
+	select {
+	case _, ok = <-updates:
+	case <-time.After(10 * time.Second):
+	}
+	require.False(t, ok)
+}
+
+func TestStreamTee(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kcs.jsonl")
+	tee, err := openTee(path)
+	require.NoError(t, err)
+	tee.write(websocket.RawFrame{Topic: websocket.THistory, Symbol: "KCS-BTC", Payload: []byte(`{}`)})
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Empty(t, b)
+
+	require.NoError(t, tee.flush())
+	b, err = os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(b), `"symbol":"KCS-BTC"`)
+
+	tee.write(websocket.RawFrame{Topic: websocket.THistory, Symbol: "ETH-BTC", Payload: []byte(`{}`)})
+	require.NoError(t, tee.Close())
+	b, err = os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, 2, strings.Count(string(b), "\n"))
+}
+
+func TestOrdersCreateExpire(t *testing.T) {
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		body := `{"success":true,"code":"OK","data":[{"coinPair":"KCS-BTC"}]}`
+		return &http.Response{StatusCode: 200, Status: "200 OK", Body: io.NopCloser(strings.NewReader(body))}, nil
+	})
+	var out bytes.Buffer
+	e := &env{
+		k:      kucoinGo.NewCustomClient("test-key", "test-secret", http.Client{Transport: transport}, kucoinGo.WithDryRun()),
+		out:    &printer{w: &out},
+		dryRun: true,
+	}
+
+	err := runOrders(e, []string{"create", "-expire", "1m", "KCS-BTC", "BUY", "0.00017", "1.5"})
+	require.Equal(t, errExpireWithoutGTT, err)
+	err = runOrders(e, []string{"create", "-tif", "IOC", "-expire", "1m", "KCS-BTC", "BUY", "0.00017", "1.5"})
+	require.Equal(t, errExpireWithoutGTT, err)
+
+	// Nothing to cancel in dry run: the command doesn't wait for the expiry.
+	start := time.Now()
+	err = runOrders(e, []string{"create", "-tif", "GTT", "-expire", "1m", "KCS-BTC", "BUY", "0.00017", "1.5"})
+	require.NoError(t, err)
+	require.Less(t, time.Since(start), 10*time.Second)
+	require.Contains(t, out.String(), "dry-run-")
+
+	// GTT orders are cancelled once expired, or at once on interrupt.
+	var mu sync.Mutex
+	var cancelled []string
+	transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		body := `{"success":true,"code":"OK","data":{}}`
+		switch path.Base(r.URL.Path) {
+		case "coins-trending":
+			body = `{"success":true,"code":"OK","data":[{"coinPair":"KCS-BTC"}]}`
+		case "order":
+			body = `{"success":true,"code":"OK","data":{"orderOid":"oid-1"}}`
+		case "cancel-order":
+			r.ParseForm()
+			mu.Lock()
+			cancelled = append(cancelled, r.PostForm.Get("orderOid"))
+			mu.Unlock()
+		}
+		return &http.Response{StatusCode: 200, Status: "200 OK", Body: io.NopCloser(strings.NewReader(body))}, nil
+	})
+	e = &env{
+		k:   kucoinGo.NewCustomClient("test-key", "test-secret", http.Client{Transport: transport}),
+		out: &printer{w: &out},
+	}
+	err = runOrders(e, []string{"create", "-tif", "GTT", "-expire", "50ms", "KCS-BTC", "BUY", "0.00017", "1.5"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"oid-1"}, cancelled)
+	require.Empty(t, e.k.PendingExpiries())
+
+	interrupt := make(chan os.Signal, 1)
+	interrupt <- os.Interrupt
+	e.interrupt = interrupt
+	start = time.Now()
+	err = runOrders(e, []string{"create", "-tif", "GTT", "-expire", "1h", "KCS-BTC", "BUY", "0.00017", "1.5"})
+	require.NoError(t, err)
+	require.Less(t, time.Since(start), 10*time.Second)
+	require.Equal(t, []string{"oid-1", "oid-1"}, cancelled)
+	require.Empty(t, e.k.PendingExpiries())
+}
+
+type roundTripFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
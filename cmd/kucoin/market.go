@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"strconv"
+	"strings"
+
+	"github.com/fiore/kucoin-go"
+)
+
+func runTicker(e *env, args []string) error {
+	fs := flag.NewFlagSet("ticker", flag.ContinueOnError)
+	if err := parseFlags(fs, args, 1, 1); err != nil {
+		return err
+	}
+	s, err := e.k.GetSymbol(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return e.out.print(s, symbolHeader, [][]string{symbolRow(s)})
+}
+
+var symbolHeader = []string{"SYMBOL", "LAST", "BUY", "SELL", "CHANGE", "HIGH", "LOW", "VOL", "TIME"}
+
+func symbolRow(s kucoin.Symbol) []string {
+	return []string{
+		s.Symbol,
+		formatFloat(s.LastDealPrice),
+		formatFloat(s.Buy),
+		formatFloat(s.Sell),
+		strconv.FormatFloat(s.ChangeRate*100, 'f', 2, 64) + "%",
+		formatFloat(s.High),
+		formatFloat(s.Low),
+		formatFloat(s.Vol),
+		formatTime(s.Datetime.Time),
+	}
+}
+
+func runBook(e *env, args []string) error {
+	fs := flag.NewFlagSet("book", flag.ContinueOnError)
+	limit := fs.Int("limit", 10, "number of levels per side")
+	group := fs.Int("group", 0, "price grouping")
+	if err := parseFlags(fs, args, 1, 1); err != nil {
+		return err
+	}
+	book, err := e.k.OrdersBook(fs.Arg(0), *group, *limit, "")
+	if err != nil {
+		return err
+	}
+	var rows [][]string
+	// Asks from the highest down to the spread, then bids.
+	for i := len(book.SELL) - 1; i >= 0; i-- {
+		rows = append(rows, levelRow(kucoin.Sell, book.SELL[i]))
+	}
+	for _, l := range book.BUY {
+		rows = append(rows, levelRow(kucoin.Buy, l))
+	}
+	return e.out.print(book, []string{"SIDE", "PRICE", "AMOUNT", "VOLUME"}, rows)
+}
+
+func levelRow(side kucoin.Side, l []float64) []string {
+	row := []string{string(side)}
+	for _, v := range l {
+		row = append(row, formatFloat(v))
+	}
+	return row
+}
+
+func runSymbols(e *env, args []string) error {
+	fs := flag.NewFlagSet("symbols", flag.ContinueOnError)
+	market := fs.String("market", "", "only list the symbols of the market, e.g. BTC")
+	if err := parseFlags(fs, args, 0, 0); err != nil {
+		return err
+	}
+	symbols, err := e.k.GetSymbols()
+	if err != nil {
+		return err
+	}
+	var res []kucoin.Symbol
+	var rows [][]string
+	for _, s := range symbols {
+		if len(*market) > 0 && !strings.EqualFold(s.CoinTypePair, *market) {
+			continue
+		}
+		res = append(res, s)
+		rows = append(rows, symbolRow(s))
+	}
+	return e.out.print(res, symbolHeader, rows)
+}
+
+func runCoins(e *env, args []string) error {
+	fs := flag.NewFlagSet("coins", flag.ContinueOnError)
+	if err := parseFlags(fs, args, 0, 0); err != nil {
+		return err
+	}
+	coins, err := e.k.GetCoins()
+	if err != nil {
+		return err
+	}
+	var rows [][]string
+	for _, c := range coins {
+		rows = append(rows, []string{
+			c.Coin,
+			c.Name,
+			strconv.FormatBool(c.EnableDeposit),
+			strconv.FormatBool(c.EnableWithdraw),
+			formatFloat(c.WithdrawMinAmount),
+			formatFloat(c.WithdrawMinFee),
+			formatFloat(c.WithdrawFeeRate),
+			strconv.Itoa(c.ConfirmationCount),
+		})
+	}
+	return e.out.print(coins, []string{"COIN", "NAME", "DEPOSIT", "WITHDRAW", "MIN AMOUNT", "MIN FEE", "FEE RATE", "CONFIRMATIONS"}, rows)
+}
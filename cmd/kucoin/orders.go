@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/fiore/kucoin-go"
+)
+
+// errExpireWithoutGTT is returned when -expire is given to a non GTT order.
+var errExpireWithoutGTT = errors.New("-expire requires -tif GTT")
+
+func runOrders(e *env, args []string) error {
+	if len(args) < 1 {
+		return errUsage
+	}
+	switch args[0] {
+	case "list":
+		return runOrdersList(e, args[1:])
+	case "create":
+		return runOrdersCreate(e, args[1:])
+	case "cancel":
+		return runOrdersCancel(e, args[1:])
+	case "cancel-all":
+		return runOrdersCancelAll(e, args[1:])
+	}
+	return errUsage
+}
+
+// orders list [-side SIDE] SYMBOL
+func runOrdersList(e *env, args []string) error {
+	fs := flag.NewFlagSet("orders list", flag.ContinueOnError)
+	side := fs.String("side", "", "only list the orders of the side, BUY or SELL")
+	if err := parseFlags(fs, args, 1, 1); err != nil {
+		return err
+	}
+	orders, err := e.k.ListActiveMapOrders(fs.Arg(0), kucoin.Side(*side))
+	if err != nil {
+		return err
+	}
+	var rows [][]string
+	for _, o := range append(orders.SELL, orders.BUY...) {
+		rows = append(rows, []string{
+			formatTime(o.CreatedAt.Time),
+			o.CoinType + "-" + o.CoinTypePair,
+			string(o.Direction),
+			formatFloat(o.Price),
+			formatFloat(o.PendingAmount),
+			formatFloat(o.DealAmount),
+			o.Oid,
+		})
+	}
+	return e.out.print(orders, []string{"TIME", "SYMBOL", "SIDE", "PRICE", "PENDING", "DEALT", "OID"}, rows)
+}
+
+// orders create [-tif GTC|IOC|FOK|GTT] [-post-only] [-expire DURATION] SYMBOL SIDE PRICE AMOUNT
+func runOrdersCreate(e *env, args []string) error {
+	fs := flag.NewFlagSet("orders create", flag.ContinueOnError)
+	tif := fs.String("tif", "", "time in force: GTC, IOC, FOK or GTT")
+	postOnly := fs.Bool("post-only", false, "reject the order if it would cross the book")
+	expire := fs.Duration("expire", 0, "expiry of GTT orders, the command waits for it and cancels on interrupt")
+	if err := parseFlags(fs, args, 4, 4); err != nil {
+		return err
+	}
+	if *expire != 0 && kucoin.TimeInForce(*tif) != kucoin.GTT {
+		return errExpireWithoutGTT
+	}
+	symbol, side, price, amount := fs.Arg(0), kucoin.Side(fs.Arg(1)), fs.Arg(2), fs.Arg(3)
+
+	var orderOid string
+	var err error
+	if len(*tif) < 1 && !*postOnly && *expire == 0 {
+		// Keep the prices and amounts as typed.
+		orderOid, err = e.k.CreateOrderByString(symbol, side, price, amount)
+	} else {
+		var p, a float64
+		if p, err = strconv.ParseFloat(price, 64); err != nil {
+			return err
+		}
+		if a, err = strconv.ParseFloat(amount, 64); err != nil {
+			return err
+		}
+		orderOid, err = e.k.CreateOrderWithOptions(symbol, side, p, a, kucoin.OrderOptions{
+			TimeInForce: kucoin.TimeInForce(*tif),
+			PostOnly:    *postOnly,
+			ExpireAfter: *expire,
+		})
+	}
+	if err != nil {
+		return err
+	}
+	if err = e.out.print(map[string]string{"orderOid": orderOid}, []string{"OID"}, [][]string{{orderOid}}); err != nil {
+		return err
+	}
+	if *expire > 0 && !e.dryRun {
+		return waitExpiry(e, symbol, side, orderOid, *expire)
+	}
+	return nil
+}
+
+// waitExpiry cancels a GTT order once expired, or at once on SIGINT and
+// SIGTERM: the order would rest on the book if the command exited first.
+func waitExpiry(e *env, symbol string, side kucoin.Side, orderOid string, expire time.Duration) error {
+	// Cancel the order from here rather than from the client's timer, which
+	// doesn't keep the process alive.
+	e.k.StopExpiries()
+	interrupt := e.interrupt
+	if interrupt == nil {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(sig)
+		interrupt = sig
+	}
+	timer := time.NewTimer(expire)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-interrupt:
+		fmt.Fprintf(os.Stderr, "kucoin: interrupted, cancelling GTT order %s\n", orderOid)
+	}
+	if err := e.k.CancelOrder(symbol, orderOid, side); err != nil {
+		return fmt.Errorf("GTT order %s is still resting on the book: %s", orderOid, err)
+	}
+	return nil
+}
+
+// orders cancel SYMBOL SIDE OID
+func runOrdersCancel(e *env, args []string) error {
+	fs := flag.NewFlagSet("orders cancel", flag.ContinueOnError)
+	if err := parseFlags(fs, args, 3, 3); err != nil {
+		return err
+	}
+	if err := e.k.CancelOrder(fs.Arg(0), fs.Arg(2), kucoin.Side(fs.Arg(1))); err != nil {
+		return err
+	}
+	return e.out.print(map[string]string{"cancelled": fs.Arg(2)}, []string{"CANCELLED"}, [][]string{{fs.Arg(2)}})
+}
+
+// orders cancel-all [-side SIDE] SYMBOL
+func runOrdersCancelAll(e *env, args []string) error {
+	fs := flag.NewFlagSet("orders cancel-all", flag.ContinueOnError)
+	side := fs.String("side", "", "only cancel the orders of the side, BUY or SELL")
+	if err := parseFlags(fs, args, 1, 1); err != nil {
+		return err
+	}
+	if err := e.k.CancelAllOrders(fs.Arg(0), kucoin.Side(*side)); err != nil {
+		return err
+	}
+	return e.out.print(map[string]string{"cancelled": fs.Arg(0)}, []string{"CANCELLED"}, [][]string{{fs.Arg(0)}})
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fiore/kucoin-go"
+	"github.com/fiore/kucoin-go/websocket"
+)
+
+// errStreamClosed is returned by stream when all its subscriptions closed.
+var errStreamClosed = errors.New("all subscriptions closed")
+
+// teeFlushInterval is how often the -tee file is flushed.
+const teeFlushInterval = time.Second
+
+// update is an update received from a subscription.
+type update struct {
+	Topic  websocket.Topic `json:"topic"`
+	Symbol string          `json:"symbol"`
+	Data   interface{}     `json:"data"`
+}
+
+// streamFilter drops trades and book updates by side and size.
+type streamFilter struct {
+	side    kucoin.Side
+	minSize float64
+}
+
+func (f streamFilter) keep(data interface{}) bool {
+	switch v := data.(type) {
+	case *websocket.History:
+		return (len(f.side) < 1 || v.Direction == f.side) && v.Count >= f.minSize
+	case *websocket.OrderBook:
+		return (len(f.side) < 1 || v.Type == f.side) && v.Count >= f.minSize
+	}
+	return true
+}
+
+// subscription is a topic and symbol subscribed by stream.
+type subscription struct {
+	tc      websocket.Topic
+	symbol  string
+	updates <-chan interface{}
+}
+
+// forward merges the updates of the subscriptions into the returned
+// channel, closed once the updates of all of them are closed or done is.
+// Closed subscriptions are reported on stderr.
+func forward(subs []subscription, done <-chan struct{}) <-chan update {
+	updates := make(chan update, 64)
+	var wg sync.WaitGroup
+	for _, s := range subs {
+		wg.Add(1)
+		go func(s subscription) {
+			defer wg.Done()
+			for {
+				var data interface{}
+				var ok bool
+				select {
+				case data, ok = <-s.updates:
+				case <-done:
+					return
+				}
+				if !ok {
+					fmt.Fprintf(os.Stderr, "kucoin: %s %s: subscription closed\n", s.tc, s.symbol)
+					return
+				}
+				if data == nil {
+					continue
+				}
+				select {
+				case updates <- update{s.tc, s.symbol, data}:
+				case <-done:
+					return
+				}
+			}
+		}(s)
+	}
+	go func() {
+		wg.Wait()
+		close(updates)
+	}()
+	return updates
+}
+
+// tee appends raw frames to a file as JSON Lines.
+type tee struct {
+	mu  sync.Mutex
+	f   *os.File
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func openTee(path string) (*tee, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(f)
+	return &tee{f: f, w: w, enc: json.NewEncoder(w)}, nil
+}
+
+func (t *tee) write(fr websocket.RawFrame) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.enc.Encode(fr); err != nil {
+		fmt.Fprintln(os.Stderr, "kucoin: tee:", err)
+	}
+}
+
+func (t *tee) flush() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.w.Flush()
+}
+
+func (t *tee) Close() error {
+	return errors.Join(t.flush(), t.f.Close())
+}
+
+// stream [-topics LIST] [-format text|ndjson] [-side SIDE] [-min-size N] [-tee FILE] SYMBOL...
+func runStream(e *env, args []string) error {
+	fs := flag.NewFlagSet("stream", flag.ContinueOnError)
+	topics := fs.String("topics", "history,book", "comma separated topics: book, history, tick, market")
+	format := fs.String("format", "text", "output format: text or ndjson")
+	side := fs.String("side", "", "only print trades and book updates of the side, BUY or SELL")
+	minSize := fs.Float64("min-size", 0, "only print trades and book updates of at least this amount")
+	teePath := fs.String("tee", "", "append the raw frames to the file as JSON Lines, flushed every second")
+	if err := parseFlags(fs, args, 1, -1); err != nil {
+		return err
+	}
+	if *format != "text" && *format != "ndjson" {
+		return errUsage
+	}
+	filter := streamFilter{minSize: *minSize}
+	if len(*side) > 0 {
+		var err error
+		if filter.side, err = kucoin.ParseSide(*side); err != nil {
+			return err
+		}
+	}
+	var tcs []websocket.Topic
+	for _, name := range strings.Split(*topics, ",") {
+		tc, err := websocket.ParseTopic(strings.TrimSpace(name))
+		if err != nil {
+			return err
+		}
+		tcs = append(tcs, tc)
+	}
+
+	var t *tee
+	// The -tee file is flushed periodically, so that it can be followed.
+	var flush <-chan time.Time
+	if len(*teePath) > 0 {
+		var err error
+		if t, err = openTee(*teePath); err != nil {
+			return err
+		}
+		defer t.Close()
+		ticker := time.NewTicker(teeFlushInterval)
+		defer ticker.Stop()
+		flush = ticker.C
+	}
+
+	ws, err := websocket.NewWS()
+	if err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	var conns []*websocket.Conn
+	defer func() {
+		close(done)
+		for _, c := range conns {
+			c.Close()
+		}
+	}()
+	var subs []subscription
+	for _, tc := range tcs {
+		for _, symbol := range fs.Args() {
+			// Market topics take markets, e.g. BTC, the others symbols.
+			symbol = strings.ToUpper(symbol)
+			c, err := ws.Subscribe(tc, symbol)
+			if err != nil {
+				return fmt.Errorf("%s %s: %s", tc, symbol, err)
+			}
+			if t != nil {
+				c.SetRaw(t.write)
+			}
+			conns = append(conns, c)
+			subs = append(subs, subscription{tc, symbol, c.Updates()})
+		}
+	}
+	updates := forward(subs, done)
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	defer signal.Stop(sig)
+	enc := json.NewEncoder(e.out.w)
+	for {
+		select {
+		case <-sig:
+			return nil
+		case <-flush:
+			if err := t.flush(); err != nil {
+				fmt.Fprintln(os.Stderr, "kucoin: tee:", err)
+			}
+		case u, ok := <-updates:
+			if !ok {
+				return errStreamClosed
+			}
+			if err, ok := u.Data.(error); ok {
+				fmt.Fprintf(os.Stderr, "kucoin: %s %s: %s\n", u.Topic, u.Symbol, err)
+				continue
+			}
+			if !filter.keep(u.Data) {
+				continue
+			}
+			if *format == "ndjson" {
+				err = enc.Encode(u)
+			} else {
+				err = printUpdate(e.out.w, u)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func printUpdate(w io.Writer, u update) (err error) {
+	switch v := u.Data.(type) {
+	case *websocket.History:
+		_, err = fmt.Fprintf(w, "%s %-10s TRADE %-4s %s x %s\n",
+			formatStreamTime(v.Time.Time), u.Symbol, v.Direction, formatFloat(v.Price), formatFloat(v.Count))
+	case *websocket.OrderBook:
+		_, err = fmt.Fprintf(w, "%s %-10s BOOK  %-4s %s x %s %s\n",
+			formatStreamTime(v.Time.Time), u.Symbol, v.Type, formatFloat(v.Price), formatFloat(v.Count), v.Action)
+	case *websocket.Market:
+		_, err = fmt.Fprintf(w, "%s %-10s %-5s last %s buy %s sell %s change %s vol %s\n",
+			formatStreamTime(v.Datetime.Time), v.Symbol, strings.ToUpper(shortTopic(u.Topic)),
+			formatFloat(v.LastDealPrice), formatFloat(v.Buy), formatFloat(v.Sell), formatFloat(v.Change), formatFloat(v.VolValue))
+	default:
+		_, err = fmt.Fprintf(w, "%s %-10s %v\n", formatStreamTime(time.Now()), u.Symbol, v)
+	}
+	return
+}
+
+func shortTopic(tc websocket.Topic) string {
+	if tc == websocket.Tick {
+		return "tick"
+	}
+	return "market"
+}
+
+func formatStreamTime(t time.Time) string {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return t.Local().Format("15:04:05.000")
+}
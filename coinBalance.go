@@ -7,10 +7,19 @@ type CoinBalance struct {
 	FreezeBalance float64 `json:"freezeBalance"`
 }
 
+// CoinBalances struct represents kucoin data model.
+type CoinBalances struct {
+	Total      int           `json:"total"`
+	Datas      []CoinBalance `json:"datas"`
+	CurrPageNo int           `json:"currPageNo"`
+	Limit      int           `json:"limit"`
+	PageNos    int           `json:"pageNos"`
+}
+
 type rawCoinBalances struct {
-	Success bool          `json:"success"`
-	Code    string        `json:"code"`
-	Data    []CoinBalance `json:"data"`
+	Success bool         `json:"success"`
+	Code    string       `json:"code"`
+	Data    CoinBalances `json:"data"`
 }
 
 type rawCoinBalance struct {
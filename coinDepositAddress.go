@@ -7,10 +7,10 @@ type CoinDepositAddress struct {
 	Context        interface{} `json:"context"`
 	UserOid        string      `json:"userOid"`
 	CoinType       string      `json:"coinType"`
-	CreatedAt      int64       `json:"createdAt"`
+	CreatedAt      Millis      `json:"createdAt"`
 	DeletedAt      interface{} `json:"deletedAt"`
-	UpdatedAt      int64       `json:"updatedAt"`
-	LastReceivedAt int64       `json:"lastReceivedAt"`
+	UpdatedAt      Millis      `json:"updatedAt"`
+	LastReceivedAt Millis      `json:"lastReceivedAt"`
 }
 
 type rawCoinDepositAddress struct {
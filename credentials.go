@@ -0,0 +1,142 @@
+package kucoin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Environment variables read by EnvCredentials by default.
+const (
+	EnvAPIKey    = "KUCOIN_API_KEY"
+	EnvAPISecret = "KUCOIN_API_SECRET"
+)
+
+// Errors returned by the credentials providers.
+var (
+	ErrCredentials         = errors.New("Can't load the API credentials")
+	ErrCredentialsFileMode = errors.New("Credentials file must not be accessible by group or others")
+)
+
+// Credentials provides the API key and secret which sign requests. It is
+// called for every signed request, so that keys can be rotated without
+// restarting: implementations must be safe for concurrent use.
+type Credentials interface {
+	Credentials() (apiKey, apiSecret string, err error)
+}
+
+// NewWithCredentials returns an instantiated Kucoin struct signing its
+// requests with the credentials of creds.
+func NewWithCredentials(creds Credentials, opts ...Option) *Kucoin {
+	client := newClient(creds, opts)
+	return &Kucoin{client: client, gtt: newCanceller()}
+}
+
+// StaticCredentials holds an API key and secret in memory. Rotate replaces
+// them for the following requests.
+type StaticCredentials struct {
+	mu        sync.RWMutex
+	apiKey    string
+	apiSecret string
+}
+
+// NewStaticCredentials returns credentials holding the API key and secret.
+func NewStaticCredentials(apiKey, apiSecret string) *StaticCredentials {
+	return &StaticCredentials{apiKey: apiKey, apiSecret: apiSecret}
+}
+
+// Credentials implements Credentials.
+func (c *StaticCredentials) Credentials() (string, string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.apiKey, c.apiSecret, nil
+}
+
+// Rotate replaces the API key and secret.
+func (c *StaticCredentials) Rotate(apiKey, apiSecret string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.apiKey, c.apiSecret = apiKey, apiSecret
+}
+
+type envCredentials struct {
+	keyVar, secretVar string
+}
+
+// EnvCredentials returns credentials read from the environment variables,
+// EnvAPIKey and EnvAPISecret if empty.
+func EnvCredentials(keyVar, secretVar string) Credentials {
+	if len(keyVar) < 1 {
+		keyVar = EnvAPIKey
+	}
+	if len(secretVar) < 1 {
+		secretVar = EnvAPISecret
+	}
+	return envCredentials{keyVar: keyVar, secretVar: secretVar}
+}
+
+func (c envCredentials) Credentials() (string, string, error) {
+	return os.Getenv(c.keyVar), os.Getenv(c.secretVar), nil
+}
+
+// FileCredentials returns credentials read from a JSON file only accessible
+// by its owner, e.g. with mode 0600:
+//
+//	{"apiKey": "...", "apiSecret": "..."}
+//
+// The file is read again when it changes.
+func FileCredentials(path string) Credentials {
+	return &fileCredentials{path: path, checkMode: true, decode: decodeCredentials}
+}
+
+func decodeCredentials(b []byte) (apiKey, apiSecret string, err error) {
+	var v struct {
+		APIKey    string `json:"apiKey"`
+		APISecret string `json:"apiSecret"`
+	}
+	err = json.Unmarshal(b, &v)
+	return v.APIKey, v.APISecret, err
+}
+
+// fileCredentials caches the credentials decoded from a file until the file
+// changes.
+type fileCredentials struct {
+	path      string
+	checkMode bool
+	decode    func([]byte) (string, string, error)
+
+	mu        sync.Mutex
+	modTime   time.Time
+	size      int64
+	apiKey    string
+	apiSecret string
+}
+
+func (c *fileCredentials) Credentials() (string, string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fi, err := os.Stat(c.path)
+	if err != nil {
+		return "", "", err
+	}
+	if c.checkMode && runtime.GOOS != "windows" && fi.Mode().Perm()&0077 != 0 {
+		return "", "", fmt.Errorf("%s: %w", c.path, ErrCredentialsFileMode)
+	}
+	if fi.ModTime().Equal(c.modTime) && fi.Size() == c.size && len(c.apiKey) > 0 {
+		return c.apiKey, c.apiSecret, nil
+	}
+	b, err := os.ReadFile(c.path)
+	if err != nil {
+		return "", "", err
+	}
+	apiKey, apiSecret, err := c.decode(b)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", c.path, err)
+	}
+	c.apiKey, c.apiSecret, c.modTime, c.size = apiKey, apiSecret, fi.ModTime(), fi.Size()
+	return apiKey, apiSecret, nil
+}
@@ -0,0 +1,147 @@
+package kucoin
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Side represents the side of an order or a deal (type or direction in Kucoin docs.).
+type Side string
+
+// Sides of an order.
+const (
+	Buy  Side = "BUY"
+	Sell Side = "SELL"
+)
+
+// ParseSide parses a side case-insensitively.
+func ParseSide(s string) (Side, error) {
+	switch side := Side(strings.ToUpper(s)); side {
+	case Buy, Sell:
+		return side, nil
+	}
+	return "", fmt.Errorf(defaultMessageWrongInput, strings.Join([]string{string(Buy), string(Sell)}, ","))
+}
+
+// Opposite returns the other side.
+func (s Side) Opposite() Side {
+	if s == Buy {
+		return Sell
+	}
+	return Buy
+}
+
+func (s Side) String() string {
+	return string(s)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Side) MarshalText() ([]byte, error) {
+	return []byte(s), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. Values are upper-cased.
+func (s *Side) UnmarshalText(b []byte) error {
+	*s = Side(strings.ToUpper(string(b)))
+	return nil
+}
+
+// HistoryType represents the type of a deposit & withdrawal record.
+type HistoryType string
+
+// Types of deposit & withdrawal records.
+const (
+	Deposit  HistoryType = "DEPOSIT"
+	Withdraw HistoryType = "WITHDRAW"
+)
+
+// ParseHistoryType parses a record type case-insensitively.
+func ParseHistoryType(s string) (HistoryType, error) {
+	switch t := HistoryType(strings.ToUpper(s)); t {
+	case Deposit, Withdraw:
+		return t, nil
+	}
+	return "", fmt.Errorf(defaultMessageWrongInput, strings.Join([]string{string(Deposit), string(Withdraw)}, ","))
+}
+
+func (t HistoryType) String() string {
+	return string(t)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t HistoryType) MarshalText() ([]byte, error) {
+	return []byte(t), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. Values are upper-cased.
+func (t *HistoryType) UnmarshalText(b []byte) error {
+	*t = HistoryType(strings.ToUpper(string(b)))
+	return nil
+}
+
+// WalletStatus represents the status of a deposit & withdrawal record.
+type WalletStatus string
+
+// Statuses of deposit & withdrawal records.
+const (
+	Finished WalletStatus = "FINISHED"
+	Canceled WalletStatus = "CANCEL"
+	Pending  WalletStatus = "PENDING"
+)
+
+// ParseWalletStatus parses a record status case-insensitively.
+func ParseWalletStatus(s string) (WalletStatus, error) {
+	switch status := WalletStatus(strings.ToUpper(s)); status {
+	case Finished, Canceled, Pending:
+		return status, nil
+	}
+	return "", fmt.Errorf(defaultMessageWrongInput, strings.Join([]string{string(Finished), string(Canceled), string(Pending)}, ","))
+}
+
+func (s WalletStatus) String() string {
+	return string(s)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s WalletStatus) MarshalText() ([]byte, error) {
+	return []byte(s), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. Values are upper-cased.
+func (s *WalletStatus) UnmarshalText(b []byte) error {
+	*s = WalletStatus(strings.ToUpper(string(b)))
+	return nil
+}
+
+// SymbolFilter represents the filter of the user symbols.
+type SymbolFilter string
+
+// Filters of the user symbols.
+const (
+	Favourite SymbolFilter = "FAVOURITE"
+	Stick     SymbolFilter = "STICK"
+)
+
+// ParseSymbolFilter parses a symbol filter case-insensitively.
+func ParseSymbolFilter(s string) (SymbolFilter, error) {
+	switch f := SymbolFilter(strings.ToUpper(s)); f {
+	case Favourite, Stick:
+		return f, nil
+	}
+	return "", fmt.Errorf(defaultMessageWrongInput, strings.Join([]string{string(Favourite), string(Stick)}, ","))
+}
+
+func (f SymbolFilter) String() string {
+	return string(f)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (f SymbolFilter) MarshalText() ([]byte, error) {
+	return []byte(f), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. Values are upper-cased.
+func (f *SymbolFilter) UnmarshalText(b []byte) error {
+	*f = SymbolFilter(strings.ToUpper(string(b)))
+	return nil
+}
@@ -0,0 +1,88 @@
+package export
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fiore/kucoin-go"
+)
+
+// Cursor is the position of an exported stream: the time of its newest
+// exported record and the ids of the records which may be fetched again.
+type Cursor struct {
+	Time kucoin.Millis            `json:"time"`
+	Seen map[string]kucoin.Millis `json:"seen,omitempty"`
+}
+
+// advance moves the cursor past records, remembering the ids of those
+// within lookback of the newest one.
+func (c Cursor) advance(records []Record, lookback time.Duration) Cursor {
+	next := Cursor{Time: c.Time, Seen: make(map[string]kucoin.Millis)}
+	for _, r := range records {
+		if r.Time.After(next.Time.Time) {
+			next.Time = kucoin.NewMillis(r.Time)
+		}
+	}
+	keep := func(id string, t time.Time) {
+		if !t.Before(next.Time.Add(-lookback)) {
+			next.Seen[id] = kucoin.NewMillis(t)
+		}
+	}
+	for id, t := range c.Seen {
+		keep(id, t.Time)
+	}
+	for _, r := range records {
+		keep(r.Id, r.Time)
+	}
+	return next
+}
+
+// Checkpoint records the exported streams: fills and the deposits and
+// withdrawals of every coin.
+type Checkpoint struct {
+	Trades Cursor            `json:"trades"`
+	Wallet map[string]Cursor `json:"wallet,omitempty"`
+}
+
+func (c *Checkpoint) cursor(key string) Cursor {
+	return c.Wallet[key]
+}
+
+// LoadCheckpoint reads the checkpoint file. A missing file is an empty checkpoint.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	cp := &Checkpoint{}
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cp, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(b, cp); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// Save writes the checkpoint file atomically.
+func (c *Checkpoint) Save(path string) error {
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err = f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,247 @@
+// Package export writes the account fills, deposits and withdrawals as
+// normalized CSV or JSON Lines records for accounting and tax reporting.
+//
+// A Checkpoint records what was exported, so nightly runs resumed from it
+// only fetch and write the new records.
+package export
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fiore/kucoin-go"
+)
+
+// mergedDealtOrdersLimit is the page size of the merged dealt orders of all symbols.
+const mergedDealtOrdersLimit = 20
+
+// DefaultLookback is the default Config.Lookback.
+const DefaultLookback = 7 * 24 * time.Hour
+
+// Kind represents the kind of an exported record.
+type Kind string
+
+// Kinds of exported records.
+const (
+	KindTrade      Kind = "TRADE"
+	KindDeposit    Kind = "DEPOSIT"
+	KindWithdrawal Kind = "WITHDRAWAL"
+)
+
+// Record is a normalized fill, deposit or withdrawal.
+// Pair holds the symbol of fills and the coin of deposits and withdrawals.
+type Record struct {
+	Time    time.Time   `json:"timestamp"`
+	Kind    Kind        `json:"kind"`
+	Pair    string      `json:"pair"`
+	Side    kucoin.Side `json:"side,omitempty"`
+	Price   float64     `json:"price"`
+	Amount  float64     `json:"amount"`
+	Value   float64     `json:"value"`
+	Fee     float64     `json:"fee"`
+	FeeCoin string      `json:"feeCoin"`
+	OrderId string      `json:"orderId,omitempty"`
+	TxId    string      `json:"txId,omitempty"`
+	Id      string      `json:"id"`
+}
+
+// Source is the account data source. *kucoin.Kucoin implements it.
+// When it implements GetAllBalances too, the wallet records of all coins
+// are exported by default.
+type Source interface {
+	ListMergedDealtOrders(symbol string, side kucoin.Side, limit, page int, since, before time.Time) (kucoin.MergedDealtOrder, error)
+	AccountHistory(coin string, side kucoin.HistoryType, status kucoin.WalletStatus, page int) (kucoin.AccountHistory, error)
+}
+
+type balancesSource interface {
+	GetAllBalances() ([]kucoin.CoinBalance, error)
+}
+
+// Config holds the export settings.
+type Config struct {
+	// From and To bound the exported records, To excluded. Zero values are unbounded.
+	From, To time.Time
+	// Coins holds the coins whose deposits and withdrawals are exported.
+	// Defaults to all coins of the account, set it to save requests.
+	Coins []string
+	// Lookback is how long before the checkpoint wallet records are fetched
+	// again, since they are exported once finished. Defaults to DefaultLookback.
+	Lookback time.Duration
+	// Checkpoint is resumed from and updated once records are written.
+	// Leave it nil to export the whole range.
+	Checkpoint *Checkpoint
+}
+
+// Export writes the fills, deposits and withdrawals of the range in time
+// order and returns the number of records written.
+// Only finished deposits and withdrawals are exported.
+func Export(ctx context.Context, src Source, w Writer, cfg Config) (n int, err error) {
+	if cfg.Lookback <= 0 {
+		cfg.Lookback = DefaultLookback
+	}
+	cp := cfg.Checkpoint
+	if cp == nil {
+		cp = &Checkpoint{}
+	}
+
+	trades, err := fetchTrades(ctx, src, cfg, cp.Trades)
+	if err != nil {
+		return
+	}
+	records := trades
+
+	coins := cfg.Coins
+	if len(coins) == 0 {
+		if b, ok := src.(balancesSource); ok {
+			var balances []kucoin.CoinBalance
+			if balances, err = b.GetAllBalances(); err != nil {
+				return
+			}
+			for _, balance := range balances {
+				coins = append(coins, balance.CoinType)
+			}
+		}
+	}
+	wallet := make(map[string][]Record)
+	for _, coin := range coins {
+		coin = strings.ToUpper(coin)
+		for _, side := range []kucoin.HistoryType{kucoin.Deposit, kucoin.Withdraw} {
+			key := walletKey(coin, side)
+			var res []Record
+			if res, err = fetchWallet(ctx, src, cfg, coin, side, cp.cursor(key)); err != nil {
+				return
+			}
+			wallet[key] = res
+			records = append(records, res...)
+		}
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Time.Before(records[j].Time)
+	})
+	for _, r := range records {
+		if err = w.Write(r); err != nil {
+			return
+		}
+		n++
+	}
+	if err = w.Flush(); err != nil {
+		return
+	}
+
+	cp.Trades = cp.Trades.advance(trades, 0)
+	for key, res := range wallet {
+		if cp.Wallet == nil {
+			cp.Wallet = make(map[string]Cursor)
+		}
+		cp.Wallet[key] = cp.cursor(key).advance(res, cfg.Lookback)
+	}
+	return
+}
+
+func walletKey(coin string, side kucoin.HistoryType) string {
+	return coin + "/" + string(side)
+}
+
+// fetchTrades pages through the merged dealt orders of all symbols.
+func fetchTrades(ctx context.Context, src Source, cfg Config, cursor Cursor) (records []Record, err error) {
+	since := cfg.From
+	if cursor.Time.After(since) {
+		since = cursor.Time.Time
+	}
+	for page := 1; ; page++ {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		var res kucoin.MergedDealtOrder
+		if res, err = src.ListMergedDealtOrders("", "", mergedDealtOrdersLimit, page, since, cfg.To); err != nil {
+			return
+		}
+		for _, d := range res.Datas {
+			if _, ok := cursor.Seen[d.Oid]; ok || !inRange(d.CreatedAt.Time, since, cfg.To) {
+				continue
+			}
+			records = append(records, tradeRecord(d))
+		}
+		if len(res.Datas) < mergedDealtOrdersLimit || page*mergedDealtOrdersLimit >= res.Total {
+			return
+		}
+	}
+}
+
+// fetchWallet pages through the finished wallet records of the coin,
+// newest first, until records get older than the range.
+func fetchWallet(ctx context.Context, src Source, cfg Config, coin string, side kucoin.HistoryType, cursor Cursor) (records []Record, err error) {
+	since := cfg.From
+	if !cursor.Time.IsZero() && cursor.Time.Add(-cfg.Lookback).After(since) {
+		since = cursor.Time.Add(-cfg.Lookback)
+	}
+	for page := 1; ; page++ {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		var res kucoin.AccountHistory
+		if res, err = src.AccountHistory(coin, side, kucoin.Finished, page); err != nil {
+			return
+		}
+		for _, r := range res.Datas {
+			if !since.IsZero() && r.CreatedAt.Before(since) {
+				return
+			}
+			if _, ok := cursor.Seen[r.Oid]; ok || !inRange(r.CreatedAt.Time, since, cfg.To) {
+				continue
+			}
+			records = append(records, walletRecord(coin, r))
+		}
+		if len(res.Datas) == 0 || res.LastPage || page >= res.PageNos {
+			return
+		}
+	}
+}
+
+func inRange(t, from, to time.Time) bool {
+	return !t.Before(from) && (to.IsZero() || t.Before(to))
+}
+
+func tradeRecord(d kucoin.MergedDeal) Record {
+	// Fees are charged in the received coin.
+	feeCoin := d.CoinType
+	if d.Direction == kucoin.Sell {
+		feeCoin = d.CoinTypePair
+	}
+	return Record{
+		Time:    d.CreatedAt.Time,
+		Kind:    KindTrade,
+		Pair:    d.CoinType + "-" + d.CoinTypePair,
+		Side:    d.Direction,
+		Price:   d.DealPrice,
+		Amount:  d.Amount,
+		Value:   d.DealValue,
+		Fee:     d.Fee,
+		FeeCoin: feeCoin,
+		OrderId: d.OrderOid,
+		Id:      d.Oid,
+	}
+}
+
+func walletRecord(coin string, r kucoin.AccountRecord) Record {
+	kind := KindDeposit
+	if r.Type == kucoin.Withdraw {
+		kind = KindWithdrawal
+	}
+	record := Record{
+		Time:    r.CreatedAt.Time,
+		Kind:    kind,
+		Pair:    coin,
+		Amount:  r.Amount,
+		Fee:     r.Fee,
+		FeeCoin: coin,
+		Id:      r.Oid,
+	}
+	if txId, ok := r.OuterWalletTxid.(string); ok {
+		record.TxId = txId
+	}
+	return record
+}
@@ -0,0 +1,99 @@
+package export_test
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	kucoinGo "github.com/fiore/kucoin-go"
+	"github.com/fiore/kucoin-go/export"
+	"github.com/stretchr/testify/require"
+)
+
+// source serves deals oldest first and wallet records newest first, like Kucoin.
+type source struct {
+	deals   []kucoinGo.MergedDeal
+	records []kucoinGo.AccountRecord
+}
+
+func (s *source) ListMergedDealtOrders(symbol string, side kucoinGo.Side, limit, page int, since, before time.Time) (res kucoinGo.MergedDealtOrder, err error) {
+	var deals []kucoinGo.MergedDeal
+	for _, d := range s.deals {
+		if (since.IsZero() || !d.CreatedAt.Before(since)) && (before.IsZero() || d.CreatedAt.Before(before)) {
+			deals = append(deals, d)
+		}
+	}
+	from, to := (page-1)*limit, page*limit
+	if from > len(deals) {
+		from = len(deals)
+	}
+	if to > len(deals) {
+		to = len(deals)
+	}
+	res.Total, res.Limit, res.Page, res.Datas = len(deals), limit, page, deals[from:to]
+	return
+}
+
+func (s *source) AccountHistory(coin string, side kucoinGo.HistoryType, status kucoinGo.WalletStatus, page int) (res kucoinGo.AccountHistory, err error) {
+	for _, r := range s.records {
+		if r.CoinType == coin && r.Type == side && r.Status == status {
+			res.Datas = append(res.Datas, r)
+		}
+	}
+	res.PageNos, res.LastPage = 1, true
+	return
+}
+
+func at(minute int) kucoinGo.Millis {
+	return kucoinGo.NewMillis(time.Date(2018, 1, 1, 0, minute, 0, 0, time.UTC))
+}
+
+func TestExport(t *testing.T) {
+	src := &source{
+		deals: []kucoinGo.MergedDeal{
+			{CreatedAt: at(1), Amount: 10, DealValue: 0.01, DealPrice: 0.001, Fee: 0.01, Oid: "d1", OrderOid: "o1", CoinType: "KCS", CoinTypePair: "BTC", Direction: kucoinGo.Buy},
+			{CreatedAt: at(3), Amount: 5, DealValue: 0.006, DealPrice: 0.0012, Fee: 0.000006, Oid: "d2", OrderOid: "o2", CoinType: "KCS", CoinTypePair: "BTC", Direction: kucoinGo.Sell},
+		},
+		records: []kucoinGo.AccountRecord{
+			{Oid: "w2", CoinType: "BTC", Type: kucoinGo.Deposit, Status: kucoinGo.Pending, Amount: 2, CreatedAt: at(4)},
+			{Oid: "w1", CoinType: "BTC", Type: kucoinGo.Deposit, Status: kucoinGo.Finished, Amount: 1, CreatedAt: at(2), OuterWalletTxid: "tx1"},
+		},
+	}
+	cp, err := export.LoadCheckpoint(filepath.Join(t.TempDir(), "checkpoint.json"))
+	require.NoError(t, err)
+	cfg := export.Config{Coins: []string{"BTC"}, Checkpoint: cp}
+
+	var buf bytes.Buffer
+	n, err := export.Export(context.Background(), src, export.NewCSVWriter(&buf, true), cfg)
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	require.Equal(t, strings.Join([]string{
+		"timestamp,kind,pair,side,price,amount,value,fee,fee_coin,order_id,tx_id,id",
+		"2018-01-01T00:01:00.000Z,TRADE,KCS-BTC,BUY,0.001,10,0.01,0.01,KCS,o1,,d1",
+		"2018-01-01T00:02:00.000Z,DEPOSIT,BTC,,0,1,0,0,BTC,,tx1,w1",
+		"2018-01-01T00:03:00.000Z,TRADE,KCS-BTC,SELL,0.0012,5,0.006,0.000006,BTC,o2,,d2",
+	}, "\n")+"\n", buf.String())
+
+	// Resumed runs only export new records, pending deposits once finished.
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	require.NoError(t, cp.Save(path))
+	cfg.Checkpoint, err = export.LoadCheckpoint(path)
+	require.NoError(t, err)
+	buf.Reset()
+	n, err = export.Export(context.Background(), src, export.NewJSONLWriter(&buf), cfg)
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+
+	src.records[0].Status = kucoinGo.Finished
+	src.deals = append(src.deals, kucoinGo.MergedDeal{CreatedAt: at(3), Amount: 1, DealPrice: 0.0012, Oid: "d3", CoinType: "KCS", CoinTypePair: "BTC", Direction: kucoinGo.Sell})
+	n, err = export.Export(context.Background(), src, export.NewJSONLWriter(&buf), cfg)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	require.Contains(t, lines[0], `"id":"d3"`)
+	require.Contains(t, lines[1], `"timestamp":"2018-01-01T00:04:00Z","kind":"DEPOSIT"`)
+}
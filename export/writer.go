@@ -0,0 +1,85 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+// TimeFormat is the format of the CSV timestamps, always in UTC.
+const TimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// Header holds the CSV column names.
+var Header = []string{"timestamp", "kind", "pair", "side", "price", "amount", "value", "fee", "fee_coin", "order_id", "tx_id", "id"}
+
+// Writer writes exported records.
+type Writer interface {
+	Write(r Record) error
+	Flush() error
+}
+
+type csvWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+// NewCSVWriter returns a Writer of CSV rows. The Header row is written
+// first when header is true, leave it false when appending to a file.
+func NewCSVWriter(w io.Writer, header bool) Writer {
+	return &csvWriter{w: csv.NewWriter(w), header: header}
+}
+
+func (c *csvWriter) Write(r Record) error {
+	if c.header {
+		c.header = false
+		if err := c.w.Write(Header); err != nil {
+			return err
+		}
+	}
+	return c.w.Write([]string{
+		r.Time.UTC().Format(TimeFormat),
+		string(r.Kind),
+		r.Pair,
+		string(r.Side),
+		formatFloat(r.Price),
+		formatFloat(r.Amount),
+		formatFloat(r.Value),
+		formatFloat(r.Fee),
+		r.FeeCoin,
+		r.OrderId,
+		r.TxId,
+		r.Id,
+	})
+}
+
+func (c *csvWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+type jsonlWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+// NewJSONLWriter returns a Writer of JSON Lines, one record per line.
+func NewJSONLWriter(w io.Writer) Writer {
+	b := bufio.NewWriter(w)
+	return &jsonlWriter{w: b, enc: json.NewEncoder(b)}
+}
+
+func (j *jsonlWriter) Write(r Record) error {
+	r.Time = r.Time.UTC().Truncate(time.Millisecond)
+	return j.enc.Encode(r)
+}
+
+func (j *jsonlWriter) Flush() error {
+	return j.w.Flush()
+}
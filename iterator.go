@@ -0,0 +1,105 @@
+package kucoin
+
+import (
+	"context"
+	"time"
+)
+
+// Iterator iterates over the items of a paged endpoint, fetching the
+// following pages on demand with the max limit of the endpoint.
+//
+//	it := k.IterMergedDealtOrders("KCS-BTC", "", time.Time{}, time.Time{})
+//	for it.Next(ctx) {
+//		deal := it.Item()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type Iterator[T any] struct {
+	fetch func(ctx context.Context, page int) (items []T, last bool, err error)
+	page  int
+	items []T
+	item  T
+	last  bool
+	err   error
+}
+
+func newIterator[T any](fetch func(ctx context.Context, page int) ([]T, bool, error)) *Iterator[T] {
+	return &Iterator[T]{fetch: fetch}
+}
+
+// Next advances to the next item, fetching the next page with ctx when needed.
+// It returns false once all items were read, ctx is done or a request failed.
+func (it *Iterator[T]) Next(ctx context.Context) bool {
+	for len(it.items) == 0 {
+		if it.err != nil || it.last {
+			return false
+		}
+		if it.err = ctx.Err(); it.err != nil {
+			return false
+		}
+		it.page++
+		it.items, it.last, it.err = it.fetch(ctx, it.page)
+		if it.err != nil {
+			return false
+		}
+		if len(it.items) == 0 {
+			it.last = true
+		}
+	}
+	it.item, it.items = it.items[0], it.items[1:]
+	return true
+}
+
+// Item returns the current item.
+func (it *Iterator[T]) Item() T {
+	return it.item
+}
+
+// Err returns the error which stopped the iteration, if any.
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
+
+// Page returns the number of the last fetched page.
+func (it *Iterator[T]) Page() int {
+	return it.page
+}
+
+// IterAccountHistory returns an iterator over all deposit & withdrawal
+// records of AccountHistory.
+func (k *Kucoin) IterAccountHistory(coin string, side HistoryType, status WalletStatus) *Iterator[AccountRecord] {
+	return newIterator(func(ctx context.Context, page int) ([]AccountRecord, bool, error) {
+		res, err := k.WithContext(ctx).AccountHistory(coin, side, status, page)
+		return res.Datas, res.LastPage || page >= res.PageNos, err
+	})
+}
+
+// IterSpecificDealtOrders returns an iterator over all dealt orders of
+// ListSpecificDealtOrders.
+func (k *Kucoin) IterSpecificDealtOrders(symbol string, side Side) *Iterator[SpecificDeal] {
+	return newIterator(func(ctx context.Context, page int) ([]SpecificDeal, bool, error) {
+		res, err := k.WithContext(ctx).ListSpecificDealtOrders(symbol, side, specificDealtOrdersLimit, page)
+		return res.Datas, res.LastPage || page >= res.PageNos, err
+	})
+}
+
+// IterMergedDealtOrders returns an iterator over all dealt orders of
+// ListMergedDealtOrders.
+func (k *Kucoin) IterMergedDealtOrders(symbol string, side Side, since, before time.Time) *Iterator[MergedDeal] {
+	limit := allMergedDealtOrdersLimit
+	if len(symbol) > 1 {
+		limit = mergedDealtOrdersLimit
+	}
+	return newIterator(func(ctx context.Context, page int) ([]MergedDeal, bool, error) {
+		res, err := k.WithContext(ctx).ListMergedDealtOrders(symbol, side, limit, page, since, before)
+		return res.Datas, len(res.Datas) < limit || page*limit >= res.Total, err
+	})
+}
+
+// IterOrderDeals returns an iterator over all deals of OrderDetails.
+func (k *Kucoin) IterOrderDeals(symbol string, side Side, orderOid string) *Iterator[OrderDeal] {
+	return newIterator(func(ctx context.Context, page int) ([]OrderDeal, bool, error) {
+		res, err := k.WithContext(ctx).OrderDetails(symbol, side, orderOid, orderDetailsLimit, page)
+		return res.DealOrders.Datas, res.DealOrders.LastPage || page >= res.DealOrders.PageNos, err
+	})
+}
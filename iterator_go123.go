@@ -0,0 +1,27 @@
+//go:build go1.23
+
+package kucoin
+
+import (
+	"context"
+	"iter"
+)
+
+// All returns the remaining items as a Go 1.23 range-over-func sequence.
+// The iteration stops after yielding a non-nil error.
+//
+//	for deal, err := range k.IterMergedDealtOrders("KCS-BTC", "", time.Time{}, time.Time{}).All(ctx) {
+//	}
+func (it *Iterator[T]) All(ctx context.Context) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for it.Next(ctx) {
+			if !yield(it.Item(), nil) {
+				return
+			}
+		}
+		if err := it.Err(); err != nil {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
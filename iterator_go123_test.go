@@ -0,0 +1,20 @@
+//go:build go1.23
+
+package kucoin_test
+
+import (
+	"context"
+	"testing"
+
+	kucoinGo "github.com/fiore/kucoin-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIteratorAll(t *testing.T) {
+	n := 0
+	for _, err := range kucoin.IterAccountHistory("TEST", "DEPOSIT", "FINISHED").All(context.Background()) {
+		require.Equal(t, kucoinGo.ErrNonExistingMarket, err)
+		n++
+	}
+	require.Equal(t, 1, n)
+}
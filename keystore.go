@@ -0,0 +1,134 @@
+package kucoin
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// ErrKeystorePassphrase is returned when a keystore can't be decrypted.
+var ErrKeystorePassphrase = errors.New("Wrong keystore passphrase")
+
+const (
+	keystoreVersion = 1
+	scryptN         = 1 << 15
+	scryptR         = 8
+	scryptP         = 1
+)
+
+// keystore is an API key and secret encrypted with AES-256-GCM, with a key
+// derived from a passphrase with scrypt.
+type keystore struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+func (ks keystore) aead(passphrase string) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), ks.Salt, ks.N, ks.R, ks.P, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// WriteKeystore encrypts the API key and secret with the passphrase into
+// the file at path, replaced atomically so that running clients reading it
+// with KeystoreCredentials switch to the new keys.
+func WriteKeystore(path, passphrase, apiKey, apiSecret string) error {
+	ks := keystore{
+		Version: keystoreVersion,
+		KDF:     "scrypt",
+		N:       scryptN,
+		R:       scryptR,
+		P:       scryptP,
+		Salt:    make([]byte, 16),
+	}
+	if _, err := rand.Read(ks.Salt); err != nil {
+		return err
+	}
+	aead, err := ks.aead(passphrase)
+	if err != nil {
+		return err
+	}
+	ks.Nonce = make([]byte, aead.NonceSize())
+	if _, err = rand.Read(ks.Nonce); err != nil {
+		return err
+	}
+	plaintext, err := json.Marshal(map[string]string{"apiKey": apiKey, "apiSecret": apiSecret})
+	if err != nil {
+		return err
+	}
+	ks.Ciphertext = aead.Seal(nil, ks.Nonce, plaintext, nil)
+	b, err := json.Marshal(ks)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err = f.Write(b); err == nil {
+		err = f.Sync()
+	}
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// KeystoreCredentials returns credentials decrypted with the passphrase from
+// a keystore written by WriteKeystore. The keystore is decrypted again when
+// it changes. It returns ErrKeystorePassphrase if the passphrase is wrong.
+func KeystoreCredentials(path, passphrase string) (Credentials, error) {
+	c := &fileCredentials{path: path, decode: func(b []byte) (string, string, error) {
+		return decryptKeystore(b, passphrase)
+	}}
+	if _, _, err := c.Credentials(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func decryptKeystore(b []byte, passphrase string) (apiKey, apiSecret string, err error) {
+	var ks keystore
+	if err = json.Unmarshal(b, &ks); err != nil {
+		return
+	}
+	if ks.Version != keystoreVersion || ks.KDF != "scrypt" {
+		return "", "", fmt.Errorf("unsupported keystore version %d, kdf %s", ks.Version, ks.KDF)
+	}
+	aead, err := ks.aead(passphrase)
+	if err != nil {
+		return
+	}
+	if len(ks.Nonce) != aead.NonceSize() {
+		return "", "", errors.New("invalid keystore nonce")
+	}
+	plaintext, err := aead.Open(nil, ks.Nonce, ks.Ciphertext, nil)
+	if err != nil {
+		return "", "", ErrKeystorePassphrase
+	}
+	return decodeCredentials(plaintext)
+}
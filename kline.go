@@ -0,0 +1,133 @@
+package kucoin
+
+import "time"
+
+// KlineInterval represents a candle resolution provided by Kucoin.
+type KlineInterval string
+
+// Candle resolutions provided by Kucoin.
+const (
+	Interval1Min  KlineInterval = "1"
+	Interval5Min  KlineInterval = "5"
+	Interval15Min KlineInterval = "15"
+	Interval30Min KlineInterval = "30"
+	Interval1Hour KlineInterval = "60"
+	Interval8Hour KlineInterval = "480"
+	Interval1Day  KlineInterval = "D"
+	Interval1Week KlineInterval = "W"
+)
+
+// Duration returns the length of a candle, zero for unknown intervals.
+func (i KlineInterval) Duration() time.Duration {
+	switch i {
+	case Interval1Min:
+		return time.Minute
+	case Interval5Min:
+		return 5 * time.Minute
+	case Interval15Min:
+		return 15 * time.Minute
+	case Interval30Min:
+		return 30 * time.Minute
+	case Interval1Hour:
+		return time.Hour
+	case Interval8Hour:
+		return 8 * time.Hour
+	case Interval1Day:
+		return 24 * time.Hour
+	case Interval1Week:
+		return 7 * 24 * time.Hour
+	}
+	return 0
+}
+
+// Kline struct represents a candle: open, high, low, close prices
+// and volume traded from Time during the interval.
+type Kline struct {
+	Time   time.Time `json:"time"`
+	Open   float64   `json:"open"`
+	High   float64   `json:"high"`
+	Low    float64   `json:"low"`
+	Close  float64   `json:"close"`
+	Volume float64   `json:"volume"`
+}
+
+// rawKlines is the TradingView format returned by the chart history endpoint.
+type rawKlines struct {
+	Status string    `json:"s"`
+	Msg    string    `json:"errmsg"`
+	Time   []int64   `json:"t"`
+	Open   []float64 `json:"o"`
+	High   []float64 `json:"h"`
+	Low    []float64 `json:"l"`
+	Close  []float64 `json:"c"`
+	Volume []float64 `json:"v"`
+}
+
+// MaxKlineGaps is the most flat candles a KlineAggregator produces for a gap
+// between trades: after a longer gap, e.g. a stream down for days, only the
+// last ones are produced.
+const MaxKlineGaps = 1000
+
+// KlineAggregator builds candles of any interval from trades, e.g. from
+// websocket History updates when Kucoin doesn't provide the resolution.
+// Intervals without trades produce flat candles with zero volume, up to
+// MaxKlineGaps in a row.
+type KlineAggregator struct {
+	interval time.Duration
+	current  *Kline
+}
+
+// NewKlineAggregator returns an aggregator building candles of interval,
+// which must be positive.
+func NewKlineAggregator(interval time.Duration) (*KlineAggregator, error) {
+	if interval <= 0 {
+		return nil, ErrIntervalRequired
+	}
+	return &KlineAggregator{interval: interval}, nil
+}
+
+// Add adds a trade to the current candle and returns the candles closed
+// by it, oldest first. Trades older than the current candle are ignored.
+func (a *KlineAggregator) Add(at time.Time, price, amount float64) (closed []Kline) {
+	start := at.Truncate(a.interval)
+	switch {
+	case a.current == nil:
+		a.current = &Kline{Time: start, Open: price, High: price, Low: price}
+	case start.Before(a.current.Time):
+		return
+	case start.After(a.current.Time):
+		closed = append(closed, *a.current)
+		last := a.current.Close
+		first := a.current.Time.Add(a.interval)
+		if start.Sub(first)/a.interval > MaxKlineGaps {
+			first = start.Add(-MaxKlineGaps * a.interval)
+		}
+		for t := first; t.Before(start); t = t.Add(a.interval) {
+			closed = append(closed, Kline{Time: t, Open: last, High: last, Low: last, Close: last})
+		}
+		a.current = &Kline{Time: start, Open: price, High: price, Low: price}
+	}
+	if price > a.current.High {
+		a.current.High = price
+	}
+	if price < a.current.Low {
+		a.current.Low = price
+	}
+	a.current.Close = price
+	a.current.Volume += amount
+	return
+}
+
+// AddTrade adds a trade of the public trade tape to the current candle
+// and returns the candles closed by it.
+func (a *KlineAggregator) AddTrade(t Trade) []Kline {
+	return a.Add(t.Time.Time, t.Price, t.Count)
+}
+
+// Current returns the candle being built, false if no trade was added yet.
+func (a *KlineAggregator) Current() (Kline, bool) {
+	if a.current == nil {
+		return Kline{}, false
+	}
+	return *a.current, true
+}
@@ -2,10 +2,13 @@
 package kucoin
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -13,14 +16,40 @@ import (
 
 const (
 	kucoinURL = "https://api.kucoin.com/v1/"
+	// klinesLimit is the max number of candles requested at once.
+	klinesLimit = 1000
+	// tradesLimit is the max number of trades returned at once.
+	tradesLimit = 100
+	// balancesLimit is the max number of balances returned at once.
+	balancesLimit = 20
+	// ordersBookLimit is the max number of order book levels returned at once.
+	ordersBookLimit = 1000
+	// specificDealtOrdersLimit is the max number of dealt orders of a symbol returned at once.
+	specificDealtOrdersLimit = 1000
+	// mergedDealtOrdersLimit is the max number of dealt orders of a symbol returned at once.
+	mergedDealtOrdersLimit = 100
+	// allMergedDealtOrdersLimit is the max number of dealt orders of all symbols returned at once.
+	allMergedDealtOrdersLimit = 20
+	// orderDetailsLimit is the max number of deals of an order returned at once.
+	orderDetailsLimit = 20
 )
 
 // Custom errors used when an input is required
 var (
-	ErrSymbolRequired    = errors.New("Symbol is required")
-	ErrAllParamsRequired = errors.New("All parameters are required")
-	ErrNonExistingSymbol = errors.New("Entered symbol doesn't exist in Kucoin")
-	ErrNonExistingMarket = errors.New("Entered market doesn't exist in Kucoin")
+	ErrSymbolRequired      = errors.New("Symbol is required")
+	ErrAllParamsRequired   = errors.New("All parameters are required")
+	ErrNonExistingSymbol   = errors.New("Entered symbol doesn't exist in Kucoin")
+	ErrNonExistingMarket   = errors.New("Entered market doesn't exist in Kucoin")
+	ErrPostOnlyWouldCross  = errors.New("Post-only order would cross the book")
+	ErrFillOrKillNotFilled = errors.New("Fill-or-kill order can't be filled entirely")
+	ErrExpireAfterRequired = errors.New("Expiry is required for GTT order")
+	ErrIntervalRequired    = errors.New("Positive interval is required")
+	ErrInvalidTimeRange    = errors.New("Start time must not be after end time")
+	ErrAddressNotAllowed   = errors.New("Withdrawal address is not in the allowlist")
+	ErrWithdrawDisabled    = errors.New("Withdrawal is disabled for the coin")
+	ErrWithdrawBelowMin    = errors.New("Withdrawal amount is below the minimum")
+	ErrWithdrawFeeTooHigh  = errors.New("Withdrawal fee exceeds the amount")
+	ErrAPIKeyRequired      = errors.New("API Key and API Secret must be set")
 )
 
 var (
@@ -70,23 +99,23 @@ func (k *Kucoin) containsOpenMarkets(openMarket string) bool {
 }
 
 // New returns an instantiated Kucoin struct.
-func New(apiKey, apiSecret string) *Kucoin {
-	client := newClient(apiKey, apiSecret)
-	return &Kucoin{client}
+func New(apiKey, apiSecret string, opts ...Option) *Kucoin {
+	client := newClient(NewStaticCredentials(apiKey, apiSecret), opts)
+	return &Kucoin{client: client, gtt: newCanceller()}
 }
 
 // NewCustomClient returns an instantiated Kucoin struct with custom http client.
-func NewCustomClient(apiKey, apiSecret string, httpClient http.Client) *Kucoin {
-	client := newClient(apiKey, apiSecret)
+func NewCustomClient(apiKey, apiSecret string, httpClient http.Client, opts ...Option) *Kucoin {
+	client := newClient(NewStaticCredentials(apiKey, apiSecret), opts)
 	client.httpClient = httpClient
-	return &Kucoin{client}
+	return &Kucoin{client: client, gtt: newCanceller()}
 }
 
 // NewCustomTimeout returns an instantiated Kucoin struct with custom timeout.
-func NewCustomTimeout(apiKey, apiSecret string, timeout time.Duration) *Kucoin {
-	client := newClient(apiKey, apiSecret)
+func NewCustomTimeout(apiKey, apiSecret string, timeout time.Duration, opts ...Option) *Kucoin {
+	client := newClient(NewStaticCredentials(apiKey, apiSecret), opts)
 	client.httpClient.Timeout = timeout
-	return &Kucoin{client}
+	return &Kucoin{client: client, gtt: newCanceller()}
 }
 
 func doArgs(args ...string) map[string]string {
@@ -128,16 +157,37 @@ func handleErr(r interface{}) error {
 // Kucoin represent a Kucoin client.
 type Kucoin struct {
 	client *client
+	gtt    *canceller
+	ctx    context.Context
 }
 
-// SetDebug enables/disables http request/response dump.
+// WithContext returns a copy of k whose requests use ctx: they are cancelled
+// with it and traced as its children.
+//
+//	oid, err := k.WithContext(ctx).CreateOrder("KCS-BTC", kucoin.Buy, 0.0001, 1)
+func (k *Kucoin) WithContext(ctx context.Context) *Kucoin {
+	k2 := *k
+	k2.ctx = ctx
+	return &k2
+}
+
+// reqContext returns the context of the requests.
+func (k *Kucoin) reqContext() context.Context {
+	if k.ctx == nil {
+		return context.Background()
+	}
+	return k.ctx
+}
+
+// SetDebug enables/disables http request/response dump of the failed calls,
+// their KC-API-* headers redacted. Disabled by default.
 func (k *Kucoin) SetDebug(enable bool) {
 	k.client.debug = enable
 }
 
 // GetUserInfo is used to get the user information at Kucoin along with other meta data.
 func (k *Kucoin) GetUserInfo() (userInfo UserInfo, err error) {
-	r, err := k.client.do("GET", "user/info", nil, true)
+	r, err := k.client.do(k.reqContext(), "GET", "user/info", nil, true)
 	if err != nil {
 		return
 	}
@@ -156,7 +206,7 @@ func (k *Kucoin) GetUserInfo() (userInfo UserInfo, err error) {
 
 // GetSymbols is used to get the all open and available trading markets at Kucoin along with other meta data.
 func (k *Kucoin) GetSymbols() (symbols []Symbol, err error) {
-	r, err := k.client.do("GET", "market/open/symbols", nil, false)
+	r, err := k.client.do(k.reqContext(), "GET", "market/open/symbols", nil, false)
 	if err != nil {
 		return
 	}
@@ -176,7 +226,7 @@ func (k *Kucoin) GetSymbols() (symbols []Symbol, err error) {
 
 // GetCoinsPairs is used to get the all available trading markets at Kucoin.
 func (k *Kucoin) GetCoinsPairs() (coinPair []CoinPair, err error) {
-	r, err := k.client.do("GET", "market/open/coins-trending", nil, true)
+	r, err := k.client.do(k.reqContext(), "GET", "market/open/coins-trending", nil, true)
 	if err != nil {
 		return
 	}
@@ -199,7 +249,7 @@ func (k *Kucoin) GetCoinsPairs() (coinPair []CoinPair, err error) {
 // - Market = BTC
 // - Symbol = KCS-BTC
 // - Filter = FAVOURITE | STICK
-func (k *Kucoin) GetUserSymbols(market, symbol, filter string) (symbols []Symbol, err error) {
+func (k *Kucoin) GetUserSymbols(market, symbol string, filter SymbolFilter) (symbols []Symbol, err error) {
 	if len(market) > 1 {
 		if !k.containsOpenMarkets(strings.ToUpper(market)) {
 			return symbols, ErrNonExistingMarket
@@ -210,19 +260,19 @@ func (k *Kucoin) GetUserSymbols(market, symbol, filter string) (symbols []Symbol
 			return symbols, ErrNonExistingSymbol
 		}
 	}
-	if len(filter) > 1 {
-		if filter != "FAVOURITE" && filter != "STICK" {
-			return symbols, fmt.Errorf(defaultMessageWrongInput, strings.Join([]string{"FAVOURITE", "STICK"}, ","))
+	if len(filter) > 0 {
+		if filter, err = ParseSymbolFilter(string(filter)); err != nil {
+			return
 		}
 	}
 
 	payload := map[string]string{
 		"symbol": strings.ToUpper(symbol),
 		"market": strings.ToUpper(market),
-		"filter": strings.ToUpper(filter),
+		"filter": string(filter),
 	}
 
-	r, err := k.client.do("GET", "market/symbols", payload, true)
+	r, err := k.client.do(k.reqContext(), "GET", "market/symbols", payload, true)
 	if err != nil {
 		return
 	}
@@ -254,7 +304,7 @@ func (k *Kucoin) GetSymbol(s string) (symbol Symbol, err error) {
 		"symbol": strings.ToUpper(s),
 	}
 
-	r, err := k.client.do("GET", "open/tick", payload, false)
+	r, err := k.client.do(k.reqContext(), "GET", "open/tick", payload, false)
 	if err != nil {
 		return
 	}
@@ -271,9 +321,157 @@ func (k *Kucoin) GetSymbol(s string) (symbol Symbol, err error) {
 	return
 }
 
+// GetKlines is used to get the candles of the symbol at Kucoin from time to time.
+// Long ranges are fetched in several requests.
+// Example:
+// - Symbol (required) = KCS-BTC
+// - Interval (required) = Interval1Hour
+// - From (required)
+// - To (required, not before From)
+func (k *Kucoin) GetKlines(symbol string, interval KlineInterval, from, to time.Time) (klines []Kline, err error) {
+	if len(symbol) < 1 || from.IsZero() || to.IsZero() {
+		return klines, ErrAllParamsRequired
+	}
+	if from.After(to) {
+		return klines, ErrInvalidTimeRange
+	}
+	step := interval.Duration()
+	if step == 0 {
+		return klines, fmt.Errorf(defaultMessageWrongInput, strings.Join([]string{"1", "5", "15", "30", "60", "480", "D", "W"}, ","))
+	}
+	if !k.containsCoinsPairs(strings.ToUpper(symbol)) {
+		return klines, ErrNonExistingSymbol
+	}
+
+	for start := from; !start.After(to); start = start.Add(step * klinesLimit) {
+		end := start.Add(step * (klinesLimit - 1))
+		if end.After(to) {
+			end = to
+		}
+		payload := map[string]string{
+			"symbol":     strings.ToUpper(symbol),
+			"resolution": string(interval),
+			"from":       fmt.Sprintf("%v", start.Unix()),
+			"to":         fmt.Sprintf("%v", end.Unix()),
+		}
+
+		var r []byte
+		r, err = k.client.do(k.reqContext(), "GET", "open/chart/history", payload, false)
+		if err != nil {
+			return
+		}
+		var response interface{}
+		if err = json.Unmarshal(r, &response); err != nil {
+			return
+		}
+		if err = handleErr(response); err != nil {
+			return
+		}
+		var rawRes rawKlines
+		if err = json.Unmarshal(r, &rawRes); err != nil {
+			return
+		}
+		switch rawRes.Status {
+		case "ok":
+		case "no_data":
+			continue
+		default:
+			return klines, errors.New(rawRes.Msg)
+		}
+		for i, t := range rawRes.Time {
+			if i >= len(rawRes.Open) || i >= len(rawRes.High) || i >= len(rawRes.Low) ||
+				i >= len(rawRes.Close) || i >= len(rawRes.Volume) {
+				break
+			}
+			kline := Kline{
+				Time:   time.Unix(t, 0),
+				Open:   rawRes.Open[i],
+				High:   rawRes.High[i],
+				Low:    rawRes.Low[i],
+				Close:  rawRes.Close[i],
+				Volume: rawRes.Volume[i],
+			}
+			// Skip candles already returned by the previous request.
+			if len(klines) > 0 && !kline.Time.After(klines[len(klines)-1].Time) {
+				continue
+			}
+			klines = append(klines, kline)
+		}
+	}
+	return
+}
+
+// GetRecentTrades is used to get the latest trades of the symbol at Kucoin, oldest first.
+// Limit may be zero, and not greater than 100. Since may be the zero time.
+// To page through the trade history, call it again with Since set to the Time
+// of the last returned trade.
+// Example:
+// - Symbol (required) = KCS-BTC
+// - Limit
+// - Since
+func (k *Kucoin) GetRecentTrades(symbol string, limit int, since time.Time) (trades []Trade, err error) {
+	if len(symbol) < 1 {
+		return trades, ErrSymbolRequired
+	}
+	if !k.containsCoinsPairs(strings.ToUpper(symbol)) {
+		return trades, ErrNonExistingSymbol
+	}
+	payload := make(map[string]string)
+	payload["symbol"] = strings.ToUpper(symbol)
+	if limit == 0 || limit > tradesLimit {
+		payload["limit"] = fmt.Sprintf("%v", tradesLimit)
+	} else {
+		payload["limit"] = fmt.Sprintf("%v", limit)
+	}
+	if !since.IsZero() {
+		payload["since"] = fmt.Sprintf("%v", millis(since))
+	}
+
+	r, err := k.client.do(k.reqContext(), "GET", "open/deal-orders", payload, false)
+	if err != nil {
+		return
+	}
+	var response interface{}
+	if err = json.Unmarshal(r, &response); err != nil {
+		return
+	}
+	if err = handleErr(response); err != nil {
+		return
+	}
+	var rawRes rawTrades
+	if err = json.Unmarshal(r, &rawRes); err != nil {
+		return
+	}
+	for _, raw := range rawRes.Data {
+		if len(raw) < 5 {
+			return trades, fmt.Errorf("don't recognized trade %v", raw)
+		}
+		var trade Trade
+		trade.Symbol = strings.ToUpper(symbol)
+		t, _ := raw[0].(float64)
+		trade.Time = MillisOf(int64(t))
+		direction, _ := raw[1].(string)
+		trade.Direction = Side(strings.ToUpper(direction))
+		trade.Price, _ = raw[2].(float64)
+		trade.Count, _ = raw[3].(float64)
+		trade.VolValue, _ = raw[4].(float64)
+		if len(raw) > 5 {
+			trade.Id, _ = raw[5].(string)
+		}
+		if !since.IsZero() && !trade.Time.After(since) {
+			continue
+		}
+		trades = append(trades, trade)
+	}
+	sort.SliceStable(trades, func(i, j int) bool {
+		return trades[i].Time.Before(trades[j].Time.Time)
+	})
+	return
+}
+
 // GetOpenMarkets is used to get all open markets.
 func (k *Kucoin) GetOpenMarkets() (markets []string, err error) {
-	r, err := k.client.do("GET", "open/markets", nil, false)
+	r, err := k.client.do(k.reqContext(), "GET", "open/markets", nil, false)
 	if err != nil {
 		return
 	}
@@ -292,7 +490,7 @@ func (k *Kucoin) GetOpenMarkets() (markets []string, err error) {
 
 // GetCoins is used to get all open and available trading coins at Kucoin along with other meta data.
 func (k *Kucoin) GetCoins() (coins []Coin, err error) {
-	r, err := k.client.do("GET", "market/open/coins", nil, false)
+	r, err := k.client.do(k.reqContext(), "GET", "market/open/coins", nil, false)
 	if err != nil {
 		return
 	}
@@ -323,7 +521,7 @@ func (k *Kucoin) GetCoin(c string) (coin Coin, err error) {
 		"coin": strings.ToUpper(c),
 	}
 
-	r, err := k.client.do("GET", "market/open/coin-info", payload, false)
+	r, err := k.client.do(k.reqContext(), "GET", "market/open/coin-info", payload, false)
 	if err != nil {
 		return
 	}
@@ -351,7 +549,7 @@ func (k *Kucoin) GetCoinBalance(coin string) (coinBalance CoinBalance, err error
 		return coinBalance, ErrNonExistingMarket
 	}
 
-	r, err := k.client.do("GET", fmt.Sprintf("account/%s/balance", strings.ToUpper(coin)), nil, true)
+	r, err := k.client.do(k.reqContext(), "GET", fmt.Sprintf("account/%s/balance", strings.ToUpper(coin)), nil, true)
 	if err != nil {
 		return
 	}
@@ -368,6 +566,56 @@ func (k *Kucoin) GetCoinBalance(coin string) (coinBalance CoinBalance, err error
 	return
 }
 
+// GetAllBalances is used to get the balances of all coins at Kucoin.
+// All pages are fetched.
+func (k *Kucoin) GetAllBalances() (coinBalances []CoinBalance, err error) {
+	for page := 1; ; page++ {
+		payload := map[string]string{
+			"limit": fmt.Sprintf("%v", balancesLimit),
+			"page":  fmt.Sprintf("%v", page),
+		}
+		var r []byte
+		r, err = k.client.do(k.reqContext(), "GET", "account/balances", payload, true)
+		if err != nil {
+			return
+		}
+		var response interface{}
+		if err = json.Unmarshal(r, &response); err != nil {
+			return
+		}
+		if err = handleErr(response); err != nil {
+			return
+		}
+		var rawRes rawCoinBalances
+		if err = json.Unmarshal(r, &rawRes); err != nil {
+			return
+		}
+		coinBalances = append(coinBalances, rawRes.Data.Datas...)
+		if len(rawRes.Data.Datas) == 0 || page >= rawRes.Data.PageNos {
+			return
+		}
+	}
+}
+
+// GetPortfolio is used to get the balances of all coins at Kucoin valued in
+// the quote coin with the last prices of GetSymbols.
+// Example:
+// - Quote (required) = BTC | USDT
+func (k *Kucoin) GetPortfolio(quote string) (portfolio Portfolio, err error) {
+	if len(quote) < 1 {
+		return portfolio, ErrSymbolRequired
+	}
+	coinBalances, err := k.GetAllBalances()
+	if err != nil {
+		return
+	}
+	symbols, err := k.GetSymbols()
+	if err != nil {
+		return
+	}
+	return NewPortfolio(coinBalances, symbols, quote), nil
+}
+
 // GetCoinDepositAddress is used to get the address at chosen coin at Kucoin along with other meta data.
 func (k *Kucoin) GetCoinDepositAddress(coin string) (coinDepositAddress CoinDepositAddress, err error) {
 	if len(coin) < 1 {
@@ -377,7 +625,7 @@ func (k *Kucoin) GetCoinDepositAddress(coin string) (coinDepositAddress CoinDepo
 		return coinDepositAddress, ErrNonExistingMarket
 	}
 
-	r, err := k.client.do("GET", fmt.Sprintf("account/%s/wallet/address", strings.ToUpper(coin)), nil, true)
+	r, err := k.client.do(k.reqContext(), "GET", fmt.Sprintf("account/%s/wallet/address", strings.ToUpper(coin)), nil, true)
 	if err != nil {
 		return
 	}
@@ -399,7 +647,7 @@ func (k *Kucoin) GetCoinDepositAddress(coin string) (coinDepositAddress CoinDepo
 // Example:
 // - Symbol (required) = KCS-BTC
 // - Type = BUY | SELL
-func (k *Kucoin) ListActiveMapOrders(symbol, side string) (activeMapOrders ActiveMapOrder, err error) {
+func (k *Kucoin) ListActiveMapOrders(symbol string, side Side) (activeMapOrders ActiveMapOrder, err error) {
 	if len(symbol) < 1 {
 		return activeMapOrders, ErrSymbolRequired
 	}
@@ -408,14 +656,14 @@ func (k *Kucoin) ListActiveMapOrders(symbol, side string) (activeMapOrders Activ
 	}
 	payload := make(map[string]string)
 	payload["symbol"] = strings.ToUpper(symbol)
-	if len(side) > 1 {
-		if side != "BUY" && side != "SELL" {
-			return activeMapOrders, fmt.Errorf(defaultMessageWrongInput, strings.Join([]string{"BUY", "SELL"}, ","))
+	if len(side) > 0 {
+		if side, err = ParseSide(string(side)); err != nil {
+			return activeMapOrders, err
 		}
-		payload["type"] = strings.ToUpper(side)
+		payload["type"] = string(side)
 	}
 
-	r, err := k.client.do("GET", "order/active-map", payload, true)
+	r, err := k.client.do(k.reqContext(), "GET", "order/active-map", payload, true)
 	if err != nil {
 		return
 	}
@@ -437,7 +685,7 @@ func (k *Kucoin) ListActiveMapOrders(symbol, side string) (activeMapOrders Activ
 // Example:
 // - Symbol (required) = KCS-BTC
 // - Type = BUY | SELL
-func (k *Kucoin) ListActiveOrders(symbol, side string) (activeOrders ActiveOrder, err error) {
+func (k *Kucoin) ListActiveOrders(symbol string, side Side) (activeOrders ActiveOrder, err error) {
 	if len(symbol) < 1 {
 		return activeOrders, ErrSymbolRequired
 	}
@@ -446,14 +694,14 @@ func (k *Kucoin) ListActiveOrders(symbol, side string) (activeOrders ActiveOrder
 	}
 	payload := make(map[string]string)
 	payload["symbol"] = strings.ToUpper(symbol)
-	if len(side) > 1 {
-		if side != "BUY" && side != "SELL" {
-			return activeOrders, fmt.Errorf(defaultMessageWrongInput, strings.Join([]string{"BUY", "SELL"}, ","))
+	if len(side) > 0 {
+		if side, err = ParseSide(string(side)); err != nil {
+			return activeOrders, err
 		}
-		payload["type"] = strings.ToUpper(side)
+		payload["type"] = string(side)
 	}
 
-	r, err := k.client.do("GET", "order/active", payload, true)
+	r, err := k.client.do(k.reqContext(), "GET", "order/active", payload, true)
 	if err != nil {
 		return
 	}
@@ -479,7 +727,7 @@ func (k *Kucoin) ListActiveOrders(symbol, side string) (activeOrders ActiveOrder
 // - Group
 // - Limit
 // - Direction = BUY | SELL
-func (k *Kucoin) OrdersBook(symbol string, group, limit int, direction string) (ordersBook OrdersBook, err error) {
+func (k *Kucoin) OrdersBook(symbol string, group, limit int, direction Side) (ordersBook OrdersBook, err error) {
 	if len(symbol) < 1 {
 		return ordersBook, ErrSymbolRequired
 	}
@@ -488,22 +736,22 @@ func (k *Kucoin) OrdersBook(symbol string, group, limit int, direction string) (
 	}
 	payload := make(map[string]string)
 	payload["symbol"] = strings.ToUpper(symbol)
-	if len(direction) > 1 {
-		if direction != "BUY" && direction != "SELL" {
-			return ordersBook, fmt.Errorf(defaultMessageWrongInput, strings.Join([]string{"BUY", "SELL"}, ","))
+	if len(direction) > 0 {
+		if direction, err = ParseSide(string(direction)); err != nil {
+			return ordersBook, err
 		}
-		payload["direction"] = strings.ToUpper(direction)
+		payload["direction"] = string(direction)
 	}
 	if group > 0 {
 		payload["group"] = fmt.Sprintf("%v", group)
 	}
-	if limit == 0 {
-		payload["limit"] = fmt.Sprintf("%v", 1000)
+	if limit == 0 || limit > ordersBookLimit {
+		payload["limit"] = fmt.Sprintf("%v", ordersBookLimit)
 	} else {
 		payload["limit"] = fmt.Sprintf("%v", limit)
 	}
 
-	r, err := k.client.do("GET", "open/orders", payload, true)
+	r, err := k.client.do(k.reqContext(), "GET", "open/orders", payload, true)
 	if err != nil {
 		return
 	}
@@ -526,24 +774,32 @@ func (k *Kucoin) OrdersBook(symbol string, group, limit int, direction string) (
 // - Side (required) = BUY | SELL
 // - Price (required) = 0.0001700
 // - Amount (required) = 1.5
-func (k *Kucoin) CreateOrder(symbol, side string, price, amount float64) (orderOid string, err error) {
+func (k *Kucoin) CreateOrder(symbol string, side Side, price, amount float64) (orderOid string, err error) {
+	defer func() {
+		k.audit("CreateOrder", map[string]string{
+			"symbol": symbol,
+			"side":   string(side),
+			"price":  strconv.FormatFloat(price, 'f', -1, 64),
+			"amount": strconv.FormatFloat(amount, 'f', -1, 64),
+		}, orderOid, err)
+	}()
 	if len(symbol) < 1 || len(side) < 1 || price <= 0.0 || amount <= 0.0 {
 		return orderOid, ErrAllParamsRequired
 	}
 	if !k.containsCoinsPairs(strings.ToUpper(symbol)) {
 		return orderOid, ErrNonExistingSymbol
 	}
-	if side != "BUY" && side != "SELL" {
-		return orderOid, fmt.Errorf(defaultMessageWrongInput, strings.Join([]string{"BUY", "SELL"}, ","))
+	if side, err = ParseSide(string(side)); err != nil {
+		return orderOid, err
 	}
 	payload := map[string]string{
 		"symbol": strings.ToUpper(symbol),
 		"amount": strconv.FormatFloat(amount, 'f', 8, 64),
 		"price":  strconv.FormatFloat(price, 'f', 8, 64),
-		"type":   strings.ToUpper(side),
+		"type":   string(side),
 	}
 
-	r, err := k.client.do("POST", "order", payload, true)
+	r, err := k.client.do(k.reqContext(), "POST", "order", payload, true)
 	if err != nil {
 		return
 	}
@@ -574,24 +830,32 @@ func (k *Kucoin) CreateOrder(symbol, side string, price, amount float64) (orderO
 // - Side (required) = BUY | SELL
 // - Price (required) = 0.0001700
 // - Amount (required) = 1.5
-func (k *Kucoin) CreateOrderByString(symbol, side, price, amount string) (orderOid string, err error) {
+func (k *Kucoin) CreateOrderByString(symbol string, side Side, price, amount string) (orderOid string, err error) {
+	defer func() {
+		k.audit("CreateOrderByString", map[string]string{
+			"symbol": symbol,
+			"side":   string(side),
+			"price":  price,
+			"amount": amount,
+		}, orderOid, err)
+	}()
 	if len(symbol) < 1 || len(side) < 1 || len(price) < 1 || len(amount) < 1 {
 		return orderOid, ErrAllParamsRequired
 	}
 	if !k.containsCoinsPairs(strings.ToUpper(symbol)) {
 		return orderOid, ErrNonExistingSymbol
 	}
-	if side != "BUY" && side != "SELL" {
-		return orderOid, fmt.Errorf(defaultMessageWrongInput, strings.Join([]string{"BUY", "SELL"}, ","))
+	if side, err = ParseSide(string(side)); err != nil {
+		return orderOid, err
 	}
 	payload := map[string]string{
 		"symbol": strings.ToUpper(symbol),
 		"amount": amount,
 		"price":  price,
-		"type":   strings.ToUpper(side),
+		"type":   string(side),
 	}
 
-	r, err := k.client.do("POST", "order", payload, true)
+	r, err := k.client.do(k.reqContext(), "POST", "order", payload, true)
 	if err != nil {
 		return
 	}
@@ -615,6 +879,102 @@ func (k *Kucoin) CreateOrderByString(symbol, side, price, amount string) (orderO
 	return
 }
 
+// CreateOrderWithOptions is used to create order at Kucoin with emulated
+// time-in-force and post-only semantics.
+// Post-only orders are rejected with ErrPostOnlyWouldCross if the price
+// crosses the best opposite price of the book. IOC and FOK orders have their
+// unfilled remainder cancelled right after placement. FOK orders are placed
+// only if the book holds enough amount at acceptable prices, which is best
+// effort: the book can change before the order reaches it. A FOK order which
+// is then partly filled returns a *PartialFillError with the filled amount,
+// one which isn't filled at all ErrFillOrKillNotFilled. GTT orders are
+// cancelled in background once ExpireAfter elapses.
+// Example:
+// - Symbol (required) = KCS-BTC
+// - Side (required) = BUY | SELL
+// - Price (required) = 0.0001700
+// - Amount (required) = 1.5
+// - Options = OrderOptions{TimeInForce: GTT, ExpireAfter: time.Minute}
+func (k *Kucoin) CreateOrderWithOptions(symbol string, side Side, price, amount float64, opts OrderOptions) (orderOid string, err error) {
+	if len(symbol) < 1 || len(side) < 1 || price <= 0.0 || amount <= 0.0 {
+		return orderOid, ErrAllParamsRequired
+	}
+	if !opts.TimeInForce.valid() {
+		return orderOid, fmt.Errorf(defaultMessageWrongInput, strings.Join([]string{"GTC", "IOC", "FOK", "GTT"}, ","))
+	}
+	if opts.TimeInForce == GTT && opts.ExpireAfter <= 0 {
+		return orderOid, ErrExpireAfterRequired
+	}
+	if !k.containsCoinsPairs(strings.ToUpper(symbol)) {
+		return orderOid, ErrNonExistingSymbol
+	}
+	if side, err = ParseSide(string(side)); err != nil {
+		return orderOid, err
+	}
+
+	if opts.PostOnly || opts.TimeInForce == FOK {
+		var ordersBook OrdersBook
+		ordersBook, err = k.OrdersBook(symbol, 0, 0, "")
+		if err != nil {
+			return
+		}
+		// Levels are [price, amount, volume], best price first.
+		opposite, crosses := ordersBook.SELL, func(p float64) bool { return price >= p }
+		if side == Sell {
+			opposite, crosses = ordersBook.BUY, func(p float64) bool { return price <= p }
+		}
+		if opts.PostOnly && len(opposite) > 0 && crosses(opposite[0][0]) {
+			return orderOid, ErrPostOnlyWouldCross
+		}
+		if opts.TimeInForce == FOK {
+			available := 0.0
+			for _, level := range opposite {
+				if len(level) < 2 || !crosses(level[0]) {
+					break
+				}
+				available += level[1]
+			}
+			if available < amount {
+				return orderOid, ErrFillOrKillNotFilled
+			}
+		}
+	}
+
+	orderOid, err = k.CreateOrder(symbol, side, price, amount)
+	if err != nil || k.client.dryRun {
+		return
+	}
+
+	switch opts.TimeInForce {
+	case IOC, FOK:
+		var orderDetails OrderDetails
+		orderDetails, err = k.OrderDetails(symbol, side, orderOid, 0, 0)
+		if err != nil {
+			// The remainder is unknown: cancel the order so that it never
+			// rests on the book.
+			err = errors.Join(err, k.CancelOrder(symbol, orderOid, side))
+			return
+		}
+		if orderDetails.PendingAmount > 0 {
+			if err = k.CancelOrder(symbol, orderOid, side); err != nil {
+				return
+			}
+			if opts.TimeInForce == FOK {
+				err = ErrFillOrKillNotFilled
+				if orderDetails.DealAmount > 0 {
+					err = &PartialFillError{OrderOid: orderOid, Filled: orderDetails.DealAmount, Cancelled: orderDetails.PendingAmount}
+				}
+			}
+		}
+	case GTT:
+		// The expiry outlives the request: keep the values of its context,
+		// e.g. the trace, but not its cancellation.
+		expiring := k.WithContext(context.WithoutCancel(k.reqContext()))
+		k.gtt.schedule(expiring, strings.ToUpper(symbol), orderOid, side, opts.ExpireAfter)
+	}
+	return
+}
+
 // AccountHistory is used to get the information about list deposit & withdrawal
 // at Kucoin along with other meta data. Coin, Side (type in Kucoin docs.)
 // and Status are required parameters. Limit and page may be zeros.
@@ -623,30 +983,30 @@ func (k *Kucoin) CreateOrderByString(symbol, side, price, amount string) (orderO
 // - Side (required) = DEPOSIT | WITHDRAW
 // - Status (required) = FINISHED | CANCEL | PENDING
 // - Page
-func (k *Kucoin) AccountHistory(coin, side, status string, page int) (accountHistory AccountHistory, err error) {
+func (k *Kucoin) AccountHistory(coin string, side HistoryType, status WalletStatus, page int) (accountHistory AccountHistory, err error) {
 	if len(coin) < 1 || len(side) < 1 || len(status) < 1 {
 		return accountHistory, ErrAllParamsRequired
 	}
 	if !k.containsOpenMarkets(strings.ToUpper(coin)) {
 		return accountHistory, ErrNonExistingMarket
 	}
-	if side != "DEPOSIT" && side != "WITHDRAW" {
-		return accountHistory, fmt.Errorf(defaultMessageWrongInput, strings.Join([]string{"DEPOSIT", "WITHDRAW"}, ","))
+	if side, err = ParseHistoryType(string(side)); err != nil {
+		return
 	}
-	if status != "FINISHED" && status != "CANCEL" && status != "PENDING" {
-		return accountHistory, fmt.Errorf(defaultMessageWrongInput, strings.Join([]string{"FINISHED", "CANCEL", "PENDING"}, ","))
+	if status, err = ParseWalletStatus(string(status)); err != nil {
+		return
 	}
 
 	payload := map[string]string{
 		"coin":   strings.ToUpper(coin),
-		"type":   strings.ToUpper(side),
-		"status": strings.ToUpper(status),
+		"type":   string(side),
+		"status": string(status),
 	}
 	if page != 0 {
 		payload["page"] = fmt.Sprintf("%v", page)
 	}
 
-	r, err := k.client.do("GET", fmt.Sprintf("account/%s/wallet/records", strings.ToUpper(coin)), payload, true)
+	r, err := k.client.do(k.reqContext(), "GET", fmt.Sprintf("account/%s/wallet/records", strings.ToUpper(coin)), payload, true)
 	if err != nil {
 		return
 	}
@@ -670,7 +1030,7 @@ func (k *Kucoin) AccountHistory(coin, side, status string, page int) (accountHis
 // - Side = BUY | SELL
 // - Limit
 // - Page
-func (k *Kucoin) ListSpecificDealtOrders(symbol, side string, limit, page int) (specificDealtOrders SpecificDealtOrder, err error) {
+func (k *Kucoin) ListSpecificDealtOrders(symbol string, side Side, limit, page int) (specificDealtOrders SpecificDealtOrder, err error) {
 	if len(symbol) < 1 {
 		return specificDealtOrders, ErrSymbolRequired
 	}
@@ -679,14 +1039,14 @@ func (k *Kucoin) ListSpecificDealtOrders(symbol, side string, limit, page int) (
 	}
 	payload := make(map[string]string)
 	payload["symbol"] = strings.ToUpper(symbol)
-	if len(side) > 1 {
-		if side != "BUY" && side != "SELL" {
-			return specificDealtOrders, fmt.Errorf(defaultMessageWrongInput, strings.Join([]string{"BUY", "SELL"}, ","))
+	if len(side) > 0 {
+		if side, err = ParseSide(string(side)); err != nil {
+			return specificDealtOrders, err
 		}
-		payload["type"] = strings.ToUpper(side)
+		payload["type"] = string(side)
 	}
-	if limit == 0 {
-		payload["limit"] = fmt.Sprintf("%v", 1000)
+	if limit == 0 || limit > specificDealtOrdersLimit {
+		payload["limit"] = fmt.Sprintf("%v", specificDealtOrdersLimit)
 	} else {
 		payload["limit"] = fmt.Sprintf("%v", limit)
 	}
@@ -694,7 +1054,7 @@ func (k *Kucoin) ListSpecificDealtOrders(symbol, side string, limit, page int) (
 		payload["page"] = fmt.Sprintf("%v", page)
 	}
 
-	r, err := k.client.do("GET", "deal-orders", payload, true)
+	r, err := k.client.do(k.reqContext(), "GET", "deal-orders", payload, true)
 	if err != nil {
 		return
 	}
@@ -713,7 +1073,7 @@ func (k *Kucoin) ListSpecificDealtOrders(symbol, side string, limit, page int) (
 
 // ListMergedDealtOrders is used to get the information about dealt orders for
 // all symbols at Kucoin along with other meta data.
-// All parameters are optional. Timestamp must be in milliseconds from Unix epoch.
+// All parameters are optional, Since and Before may be the zero time.
 // Example:
 // - Symbol = KCS-BTC
 // - Side = BUY | SELL
@@ -721,7 +1081,7 @@ func (k *Kucoin) ListSpecificDealtOrders(symbol, side string, limit, page int) (
 // - Page
 // - Since
 // - Before
-func (k *Kucoin) ListMergedDealtOrders(symbol, side string, limit, page int, since, before int64) (mergedDealtOrders MergedDealtOrder, err error) {
+func (k *Kucoin) ListMergedDealtOrders(symbol string, side Side, limit, page int, since, before time.Time) (mergedDealtOrders MergedDealtOrder, err error) {
 	payload := make(map[string]string)
 	if len(symbol) > 1 {
 		if !k.containsCoinsPairs(strings.ToUpper(symbol)) {
@@ -729,30 +1089,32 @@ func (k *Kucoin) ListMergedDealtOrders(symbol, side string, limit, page int, sin
 		}
 		payload["symbol"] = strings.ToUpper(symbol)
 	}
-	if len(side) > 1 {
-		if side != "BUY" && side != "SELL" {
-			return mergedDealtOrders, fmt.Errorf(defaultMessageWrongInput, strings.Join([]string{"BUY", "SELL"}, ","))
+	if len(side) > 0 {
+		if side, err = ParseSide(string(side)); err != nil {
+			return mergedDealtOrders, err
 		}
-		payload["type"] = strings.ToUpper(side)
+		payload["type"] = string(side)
 	}
-	if (limit == 0 || limit > 100) && len(symbol) > 1 {
-		payload["limit"] = fmt.Sprintf("%v", 100)
-	} else if (limit == 0 || limit > 20) && len(symbol) < 1 {
-		payload["limit"] = fmt.Sprintf("%v", 20)
+	maxLimit := allMergedDealtOrdersLimit
+	if len(symbol) > 1 {
+		maxLimit = mergedDealtOrdersLimit
+	}
+	if limit == 0 || limit > maxLimit {
+		payload["limit"] = fmt.Sprintf("%v", maxLimit)
 	} else {
 		payload["limit"] = fmt.Sprintf("%v", limit)
 	}
 	if page != 0 {
 		payload["page"] = fmt.Sprintf("%v", page)
 	}
-	if since != 0 {
-		payload["since"] = fmt.Sprintf("%v", since)
+	if !since.IsZero() {
+		payload["since"] = fmt.Sprintf("%v", millis(since))
 	}
-	if before != 0 {
-		payload["before"] = fmt.Sprintf("%v", before)
+	if !before.IsZero() {
+		payload["before"] = fmt.Sprintf("%v", millis(before))
 	}
 
-	r, err := k.client.do("GET", "order/dealt", payload, true)
+	r, err := k.client.do(k.reqContext(), "GET", "order/dealt", payload, true)
 	if err != nil {
 		return
 	}
@@ -778,23 +1140,23 @@ func (k *Kucoin) ListMergedDealtOrders(symbol, side string, limit, page int, sin
 // - OrderOid (required)
 // - Limit
 // - Page
-func (k *Kucoin) OrderDetails(symbol, side, orderOid string, limit, page int) (orderDetails OrderDetails, err error) {
+func (k *Kucoin) OrderDetails(symbol string, side Side, orderOid string, limit, page int) (orderDetails OrderDetails, err error) {
 	if len(symbol) < 1 || len(side) < 1 || len(orderOid) < 1 {
 		return orderDetails, ErrAllParamsRequired
 	}
 	if !k.containsCoinsPairs(strings.ToUpper(symbol)) {
 		return orderDetails, ErrNonExistingSymbol
 	}
-	if side != "BUY" && side != "SELL" {
-		return orderDetails, fmt.Errorf(defaultMessageWrongInput, strings.Join([]string{"BUY", "SELL"}, ","))
+	if side, err = ParseSide(string(side)); err != nil {
+		return orderDetails, err
 	}
 	payload := map[string]string{
 		"symbol":   strings.ToUpper(symbol),
-		"type":     strings.ToUpper(side),
+		"type":     string(side),
 		"orderOid": strings.ToUpper(orderOid),
 	}
-	if limit == 0 {
-		payload["limit"] = fmt.Sprintf("%v", 20)
+	if limit == 0 || limit > orderDetailsLimit {
+		payload["limit"] = fmt.Sprintf("%v", orderDetailsLimit)
 	} else {
 		payload["limit"] = fmt.Sprintf("%v", limit)
 	}
@@ -804,7 +1166,7 @@ func (k *Kucoin) OrderDetails(symbol, side, orderOid string, limit, page int) (o
 		payload["page"] = fmt.Sprintf("%v", page)
 	}
 
-	r, err := k.client.do("GET", "order/detail", payload, true)
+	r, err := k.client.do(k.reqContext(), "GET", "order/detail", payload, true)
 	if err != nil {
 		return
 	}
@@ -821,27 +1183,78 @@ func (k *Kucoin) OrderDetails(symbol, side, orderOid string, limit, page int) (o
 	return
 }
 
-// CreateWithdrawalApply is used to create withdrawal for specific coin
-// at Kucoin along with other meta data.
+// PreflightWithdrawal checks a withdrawal without creating it: the address
+// against the allowlist set with WithWithdrawalAllowlist, then the amount
+// against the coin withdrawal settings. The fee is the greater of the
+// minimum fee and the fee rate applied to amount, and is deducted from it.
 // Example:
 // - Coin (required) = KCS
 // - Address (required) =
 // - Amount (required) = 0.50
 // Result:
-// - Nothing.
-func (k *Kucoin) CreateWithdrawalApply(coin, address string, amount float64) (withdrawalApply Withdrawal, err error) {
+// - Withdrawal with Fee and NetAmount.
+func (k *Kucoin) PreflightWithdrawal(coin, address string, amount float64) (withdrawal Withdrawal, err error) {
 	if len(coin) < 1 || len(address) < 1 || amount <= 0.0 {
-		return withdrawalApply, ErrAllParamsRequired
+		return withdrawal, ErrAllParamsRequired
 	}
-	if !k.containsOpenMarkets(strings.ToUpper(coin)) {
-		return withdrawalApply, ErrNonExistingMarket
+	coin = strings.ToUpper(coin)
+	if !k.containsOpenMarkets(coin) {
+		return withdrawal, ErrNonExistingMarket
+	}
+	if k.client.allowlist != nil {
+		if _, ok := k.client.allowlist[coin][address]; !ok {
+			return withdrawal, ErrAddressNotAllowed
+		}
+	}
+	c, err := k.GetCoin(coin)
+	if err != nil {
+		return
+	}
+	if !c.EnableWithdraw {
+		return withdrawal, ErrWithdrawDisabled
+	}
+	if amount < c.WithdrawMinAmount {
+		return withdrawal, ErrWithdrawBelowMin
+	}
+	fee := math.Max(c.WithdrawMinFee, amount*c.WithdrawFeeRate)
+	if fee >= amount {
+		return withdrawal, ErrWithdrawFeeTooHigh
+	}
+	withdrawal = Withdrawal{
+		Coin:      coin,
+		Address:   address,
+		Amount:    amount,
+		Fee:       fee,
+		NetAmount: amount - fee,
+	}
+	return
+}
+
+// CreateWithdrawalApply is used to create withdrawal for specific coin
+// at Kucoin along with other meta data, once it passes PreflightWithdrawal.
+// Example:
+// - Coin (required) = KCS
+// - Address (required) =
+// - Amount (required) = 0.50
+// Result:
+// - Withdrawal with TxOid to cancel it with CancelWithdrawal.
+func (k *Kucoin) CreateWithdrawalApply(coin, address string, amount float64) (withdrawalApply Withdrawal, err error) {
+	defer func() {
+		k.audit("CreateWithdrawalApply", map[string]string{
+			"coin":    coin,
+			"address": address,
+			"amount":  strconv.FormatFloat(amount, 'f', -1, 64),
+		}, withdrawalApply.TxOid, err)
+	}()
+	if withdrawalApply, err = k.PreflightWithdrawal(coin, address, amount); err != nil {
+		return
 	}
 	payload := map[string]string{
 		"address": address,
-		"amount":  fmt.Sprintf("%v", amount),
+		"amount":  strconv.FormatFloat(amount, 'f', -1, 64),
 	}
 
-	r, err := k.client.do("POST", fmt.Sprintf("account/%s/withdraw/apply", strings.ToUpper(coin)), payload, true)
+	r, err := k.client.do(k.reqContext(), "POST", fmt.Sprintf("account/%s/withdraw/apply", strings.ToUpper(coin)), payload, true)
 	if err != nil {
 		return
 	}
@@ -854,7 +1267,7 @@ func (k *Kucoin) CreateWithdrawalApply(coin, address string, amount float64) (wi
 	}
 	var rawRes rawWithdrawal
 	err = json.Unmarshal(r, &rawRes)
-	withdrawalApply = rawRes.Data
+	withdrawalApply.TxOid = rawRes.Data.TxOid
 	return
 }
 
@@ -866,6 +1279,9 @@ func (k *Kucoin) CreateWithdrawalApply(coin, address string, amount float64) (wi
 // Result:
 // - Nothing.
 func (k *Kucoin) CancelWithdrawal(coin, txOid string) (withdrawal Withdrawal, err error) {
+	defer func() {
+		k.audit("CancelWithdrawal", map[string]string{"coin": coin, "txOid": txOid}, txOid, err)
+	}()
 	if len(coin) < 1 || len(txOid) < 1 {
 		return withdrawal, ErrAllParamsRequired
 	}
@@ -876,7 +1292,7 @@ func (k *Kucoin) CancelWithdrawal(coin, txOid string) (withdrawal Withdrawal, er
 		"txOid": txOid,
 	}
 
-	r, err := k.client.do("POST", fmt.Sprintf("account/%s/withdraw/cancel", strings.ToUpper(coin)), payload, true)
+	r, err := k.client.do(k.reqContext(), "POST", fmt.Sprintf("account/%s/withdraw/cancel", strings.ToUpper(coin)), payload, true)
 	if err != nil {
 		return
 	}
@@ -898,23 +1314,30 @@ func (k *Kucoin) CancelWithdrawal(coin, txOid string) (withdrawal Withdrawal, er
 // - Symbol (required) = KCS-BTC
 // - OrderId (required)
 // - Side (required) = BUY | SELL
-func (k *Kucoin) CancelOrder(symbol, orderOid, side string) error {
+func (k *Kucoin) CancelOrder(symbol, orderOid string, side Side) (err error) {
+	defer func() {
+		k.audit("CancelOrder", map[string]string{
+			"symbol":   symbol,
+			"orderOid": orderOid,
+			"side":     string(side),
+		}, orderOid, err)
+	}()
 	if len(symbol) < 1 || len(side) < 1 || len(orderOid) < 1 {
 		return ErrAllParamsRequired
 	}
 	if !k.containsCoinsPairs(strings.ToUpper(symbol)) {
 		return ErrNonExistingSymbol
 	}
-	if side != "BUY" && side != "SELL" {
-		return fmt.Errorf(defaultMessageWrongInput, strings.Join([]string{"BUY", "SELL"}, ","))
+	if side, err = ParseSide(string(side)); err != nil {
+		return err
 	}
 	payload := map[string]string{
 		"symbol":   strings.ToUpper(symbol),
 		"orderOid": orderOid,
-		"type":     strings.ToUpper(side),
+		"type":     string(side),
 	}
 
-	r, err := k.client.do("POST", "cancel-order", payload, true)
+	r, err := k.client.do(k.reqContext(), "POST", "cancel-order", payload, true)
 	if err != nil {
 		return err
 	}
@@ -922,14 +1345,21 @@ func (k *Kucoin) CancelOrder(symbol, orderOid, side string) error {
 	if err = json.Unmarshal(r, &response); err != nil {
 		return err
 	}
-	return handleErr(response)
+	if err = handleErr(response); err != nil {
+		return err
+	}
+	k.gtt.stop(orderOid)
+	return nil
 }
 
 // CancelAllOrders is used to cancel execution of all orders at Kucoin along with other meta data.
 // Example:
 // - Symbol (required) = KCS-BTC
 // - Side = BUY | SELL
-func (k *Kucoin) CancelAllOrders(symbol, side string) error {
+func (k *Kucoin) CancelAllOrders(symbol string, side Side) (err error) {
+	defer func() {
+		k.audit("CancelAllOrders", map[string]string{"symbol": symbol, "side": string(side)}, "", err)
+	}()
 	if len(symbol) < 1 {
 		return ErrSymbolRequired
 	}
@@ -938,14 +1368,16 @@ func (k *Kucoin) CancelAllOrders(symbol, side string) error {
 	}
 	payload := make(map[string]string)
 	payload["symbol"] = strings.ToUpper(symbol)
-	if len(side) > 1 {
-		if side != "BUY" && side != "SELL" {
-			return fmt.Errorf(defaultMessageWrongInput, strings.Join([]string{"BUY", "SELL"}, ","))
+	if len(side) > 0 {
+		parsed, err := ParseSide(string(side))
+		if err != nil {
+			return err
 		}
-		payload["type"] = strings.ToUpper(side)
+		side = parsed
+		payload["type"] = string(side)
 	}
 
-	r, err := k.client.do("POST", "order/cancel-all", payload, true)
+	r, err := k.client.do(k.reqContext(), "POST", "order/cancel-all", payload, true)
 	if err != nil {
 		return err
 	}
@@ -953,5 +1385,9 @@ func (k *Kucoin) CancelAllOrders(symbol, side string) error {
 	if err = json.Unmarshal(r, &response); err != nil {
 		return err
 	}
-	return handleErr(response)
+	if err = handleErr(response); err != nil {
+		return err
+	}
+	k.gtt.stopSymbol(strings.ToUpper(symbol), side)
+	return nil
 }
@@ -1,23 +1,46 @@
 package kucoin_test
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	kucoinGo "github.com/fiore/kucoin-go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
+// Tests reaching Kucoin use the account of the KUCOIN_API_KEY and
+// KUCOIN_API_SECRET environment variables.
+var (
+	apiKey    = os.Getenv(kucoinGo.EnvAPIKey)
+	apiSecret = os.Getenv(kucoinGo.EnvAPISecret)
+)
+
+// Credentials of the tests with a stubbed transport.
 const (
-	// email: h2683915@nwytg.net
-	// password: Test10000
-	apiKey    = "5bd714589b5f4527c83152fb"
-	apiSecret = "62d7852d-9515-49aa-905b-9e4d19220af9"
+	testKey    = "test-key"
+	testSecret = "test-secret"
 )
 
 var (
-	kucoin              *kucoinGo.Kucoin = kucoinGo.New(apiKey, apiSecret)
+	kucoin              *kucoinGo.Kucoin = kucoinGo.NewWithCredentials(kucoinGo.EnvCredentials("", ""))
 	defaultErrorMessage string           = "There should be no error"
 )
 
@@ -73,6 +96,77 @@ func TestGetSymbol(t *testing.T) {
 	require.NoError(t, err, defaultErrorMessage)
 }
 
+func TestGetKlines(t *testing.T) {
+	to := time.Now()
+	from := to.Add(-24 * time.Hour)
+	_, err := kucoin.GetKlines("", kucoinGo.Interval1Hour, from, to)
+	if assert.Error(t, err) {
+		require.Equal(t, kucoinGo.ErrAllParamsRequired, err)
+	}
+	_, err = kucoin.GetKlines("KCS-BTC", kucoinGo.Interval1Hour, to, from)
+	if assert.Error(t, err) {
+		require.Equal(t, kucoinGo.ErrInvalidTimeRange, err)
+	}
+	_, err = kucoin.GetKlines("KCS-BTC", "TEST", from, to)
+	if assert.Error(t, err) {
+		require.Equal(t, errors.New("Entered invalid parameter. Accepted values: [1,5,15,30,60,480,D,W]"), err)
+	}
+	_, err = kucoin.GetKlines("TEST", kucoinGo.Interval1Hour, from, to)
+	if assert.Error(t, err) {
+		require.Equal(t, kucoinGo.ErrNonExistingSymbol, err)
+	}
+
+	klines, err := kucoin.GetKlines("KCS-BTC", kucoinGo.Interval1Hour, from, to)
+	t.Logf("GetKlines : %#v\n", klines)
+	require.NoError(t, err, defaultErrorMessage)
+}
+
+func TestGetRecentTrades(t *testing.T) {
+	_, err := kucoin.GetRecentTrades("", 0, time.Time{})
+	if assert.Error(t, err) {
+		require.Equal(t, kucoinGo.ErrSymbolRequired, err)
+	}
+	_, err = kucoin.GetRecentTrades("TEST", 0, time.Time{})
+	if assert.Error(t, err) {
+		require.Equal(t, kucoinGo.ErrNonExistingSymbol, err)
+	}
+
+	trades, err := kucoin.GetRecentTrades("KCS-BTC", 10, time.Time{})
+	t.Logf("GetRecentTrades : %#v\n", trades)
+	require.NoError(t, err, defaultErrorMessage)
+}
+
+func TestKlineAggregator(t *testing.T) {
+	start := time.Date(2018, 10, 1, 0, 0, 0, 0, time.UTC)
+	_, err := kucoinGo.NewKlineAggregator(0)
+	require.Equal(t, kucoinGo.ErrIntervalRequired, err)
+	a, err := kucoinGo.NewKlineAggregator(time.Minute)
+	require.NoError(t, err, defaultErrorMessage)
+	_, ok := a.Current()
+	require.False(t, ok)
+
+	require.Empty(t, a.Add(start.Add(10*time.Second), 1, 1))
+	require.Empty(t, a.Add(start.Add(20*time.Second), 3, 2))
+	require.Empty(t, a.Add(start.Add(30*time.Second), 2, 1))
+	closed := a.Add(start.Add(150*time.Second), 4, 1)
+	require.Equal(t, []kucoinGo.Kline{
+		{Time: start, Open: 1, High: 3, Low: 1, Close: 2, Volume: 4},
+		{Time: start.Add(time.Minute), Open: 2, High: 2, Low: 2, Close: 2},
+	}, closed)
+
+	current, ok := a.Current()
+	require.True(t, ok)
+	require.Equal(t, kucoinGo.Kline{Time: start.Add(2 * time.Minute), Open: 4, High: 4, Low: 4, Close: 4, Volume: 1}, current)
+
+	// Long gaps only produce the last MaxKlineGaps flat candles.
+	next := start.Add(30 * 24 * time.Hour)
+	closed = a.Add(next, 5, 1)
+	require.Len(t, closed, kucoinGo.MaxKlineGaps+1)
+	require.Equal(t, current, closed[0])
+	require.Equal(t, kucoinGo.Kline{Time: next.Add(-kucoinGo.MaxKlineGaps * time.Minute), Open: 4, High: 4, Low: 4, Close: 4}, closed[1])
+	require.Equal(t, next.Add(-time.Minute), closed[kucoinGo.MaxKlineGaps].Time)
+}
+
 func TestGetCoins(t *testing.T) {
 	coins, err := kucoin.GetCoins()
 	t.Logf("GetCoins : %#v\n", coins)
@@ -109,6 +203,51 @@ func TestGetCoinBalance(t *testing.T) {
 	require.NoError(t, err, defaultErrorMessage)
 }
 
+func TestGetAllBalances(t *testing.T) {
+	coinBalances, err := kucoin.GetAllBalances()
+	t.Logf("GetAllBalances : %#v\n", coinBalances)
+	require.NoError(t, err, defaultErrorMessage)
+}
+
+func TestGetPortfolio(t *testing.T) {
+	_, err := kucoin.GetPortfolio("")
+	if assert.Error(t, err) {
+		require.Equal(t, kucoinGo.ErrSymbolRequired, err)
+	}
+
+	portfolio, err := kucoin.GetPortfolio("BTC")
+	t.Logf("GetPortfolio : %#v\n", portfolio)
+	require.NoError(t, err, defaultErrorMessage)
+}
+
+func TestNewPortfolio(t *testing.T) {
+	portfolio := kucoinGo.NewPortfolio([]kucoinGo.CoinBalance{
+		{CoinType: "BTC", Balance: 1},
+		{CoinType: "KCS", Balance: 100, FreezeBalance: 100},
+		{CoinType: "NEO", Balance: 10},
+		{CoinType: "ETH"},
+		{CoinType: "XYZ", Balance: 1},
+	}, []kucoinGo.Symbol{
+		{Symbol: "KCS-ETH", CoinType: "KCS", CoinTypePair: "ETH", LastDealPrice: 0.01},
+		{Symbol: "ETH-BTC", CoinType: "ETH", CoinTypePair: "BTC", LastDealPrice: 0.05},
+		{Symbol: "BTC-USDT", CoinType: "BTC", CoinTypePair: "USDT", LastDealPrice: 5000},
+		{Symbol: "NEO-USDT", CoinType: "NEO", CoinTypePair: "USDT", LastDealPrice: 10},
+	}, "BTC")
+
+	require.Len(t, portfolio.Items, 3)
+	require.Equal(t, "BTC", portfolio.Items[0].CoinType)
+	require.Equal(t, "KCS", portfolio.Items[1].CoinType)
+	require.Equal(t, []string{"KCS-ETH", "ETH-BTC"}, portfolio.Items[1].Route)
+	require.InDelta(t, 0.0005, portfolio.Items[1].Price, 1e-12)
+	require.Equal(t, "NEO", portfolio.Items[2].CoinType)
+	require.Equal(t, []string{"NEO-USDT", "BTC-USDT"}, portfolio.Items[2].Route)
+	require.InDelta(t, 0.002, portfolio.Items[2].Price, 1e-12)
+	require.InDelta(t, 1+0.05+0.02, portfolio.Free, 1e-12)
+	require.InDelta(t, 0.05, portfolio.Frozen, 1e-12)
+	require.InDelta(t, 1.12, portfolio.Total, 1e-12)
+	require.Len(t, portfolio.Unvalued, 1)
+}
+
 func TestGetCoinDepositAddress(t *testing.T) {
 	_, err := kucoin.GetCoinDepositAddress("")
 	if assert.Error(t, err) {
@@ -222,6 +361,66 @@ func TestCreateOrderByString(t *testing.T) {
 	// require.NoError(t, err, defaultErrorMessage)
 }
 
+func TestCreateOrderWithOptions(t *testing.T) {
+	_, err := kucoin.CreateOrderWithOptions("", "", 0, 0, kucoinGo.OrderOptions{})
+	if assert.Error(t, err) {
+		require.Equal(t, kucoinGo.ErrAllParamsRequired, err)
+	}
+	_, err = kucoin.CreateOrderWithOptions("KCS-BTC", "BUY", 1, 1, kucoinGo.OrderOptions{TimeInForce: "TEST"})
+	if assert.Error(t, err) {
+		require.Equal(t, errors.New("Entered invalid parameter. Accepted values: [GTC,IOC,FOK,GTT]"), err)
+	}
+	_, err = kucoin.CreateOrderWithOptions("KCS-BTC", "BUY", 1, 1, kucoinGo.OrderOptions{TimeInForce: kucoinGo.GTT})
+	if assert.Error(t, err) {
+		require.Equal(t, kucoinGo.ErrExpireAfterRequired, err)
+	}
+	_, err = kucoin.CreateOrderWithOptions("TEST", "BUY", 1, 1, kucoinGo.OrderOptions{})
+	if assert.Error(t, err) {
+		require.Equal(t, kucoinGo.ErrNonExistingSymbol, err)
+	}
+	_, err = kucoin.CreateOrderWithOptions("KCS-BTC", "TEST", 1, 1, kucoinGo.OrderOptions{})
+	if assert.Error(t, err) {
+		require.Equal(t, errors.New("Entered invalid parameter. Accepted values: [BUY,SELL]"), err)
+	}
+	_, err = kucoin.CreateOrderWithOptions("KCS-BTC", "BUY", 1, 1, kucoinGo.OrderOptions{PostOnly: true})
+	if assert.Error(t, err) {
+		require.Equal(t, kucoinGo.ErrPostOnlyWouldCross, err)
+	}
+
+	/*	Use the code below only with a real account	*/
+
+	// orderOid, err := kucoin.CreateOrderWithOptions("KCS-BTC", "BUY", 0.0001700, 1.5, kucoinGo.OrderOptions{TimeInForce: kucoinGo.GTT, ExpireAfter: time.Minute})
+	// t.Logf("CreateOrderWithOptions : %#v\n", orderOid)
+	// require.NoError(t, err, defaultErrorMessage)
+}
+
+func TestRiskGuard(t *testing.T) {
+	guard := kucoinGo.NewRiskGuard(kucoin, kucoinGo.RiskLimits{MaxOrderNotional: 1})
+	_, err := guard.CreateOrder("KCS-BTC", "BUY", 1, 2)
+	if assert.Error(t, err) {
+		require.Equal(t, &kucoinGo.RiskError{Rule: kucoinGo.RuleOrderNotional, Limit: 1, Value: 2}, err)
+	}
+	_, err = guard.CreateOrderByString("KCS-BTC", "BUY", "1", "2")
+	if assert.Error(t, err) {
+		require.Equal(t, &kucoinGo.RiskError{Rule: kucoinGo.RuleOrderNotional, Limit: 1, Value: 2}, err)
+	}
+
+	err = guard.Kill()
+	require.NoError(t, err, defaultErrorMessage)
+	require.True(t, guard.Killed())
+	_, err = guard.CreateOrder("KCS-BTC", "BUY", 0.0001, 1)
+	if assert.Error(t, err) {
+		require.Equal(t, kucoinGo.ErrKillSwitch, err)
+	}
+	// Copies bound to a context share the kill switch.
+	_, err = guard.WithContext(context.Background()).CreateOrder("KCS-BTC", "BUY", 0.0001, 1)
+	if assert.Error(t, err) {
+		require.Equal(t, kucoinGo.ErrKillSwitch, err)
+	}
+	guard.Resume()
+	require.False(t, guard.Killed())
+}
+
 func TestAccountHistory(t *testing.T) {
 	_, err := kucoin.AccountHistory("", "", "", 0)
 	if assert.Error(t, err) {
@@ -267,20 +466,43 @@ func TestListSpecificDealtOrders(t *testing.T) {
 }
 
 func TestListMergedDealtOrders(t *testing.T) {
-	_, err := kucoin.ListMergedDealtOrders("TEST", "", 0, 0, 0, 0)
+	_, err := kucoin.ListMergedDealtOrders("TEST", "", 0, 0, time.Time{}, time.Time{})
 	if assert.Error(t, err) {
 		require.Equal(t, kucoinGo.ErrNonExistingSymbol, err)
 	}
-	_, err = kucoin.ListMergedDealtOrders("KCS-BTC", "TEST", 0, 0, 0, 0)
+	_, err = kucoin.ListMergedDealtOrders("KCS-BTC", "TEST", 0, 0, time.Time{}, time.Time{})
 	if assert.Error(t, err) {
 		require.Equal(t, errors.New("Entered invalid parameter. Accepted values: [BUY,SELL]"), err)
 	}
 
-	mergedDealtOrders, err := kucoin.ListMergedDealtOrders("", "", 0, 0, 0, 0)
+	mergedDealtOrders, err := kucoin.ListMergedDealtOrders("", "", 0, 0, time.Time{}, time.Time{})
 	t.Logf("ListMergedDealtOrders : %#v\n", mergedDealtOrders)
 	require.NoError(t, err, defaultErrorMessage)
 }
 
+func TestIterators(t *testing.T) {
+	ctx := context.Background()
+	it := kucoin.IterSpecificDealtOrders("", "")
+	require.False(t, it.Next(ctx))
+	require.Equal(t, kucoinGo.ErrSymbolRequired, it.Err())
+
+	history := kucoin.IterAccountHistory("TEST", "DEPOSIT", "FINISHED")
+	require.False(t, history.Next(ctx))
+	require.Equal(t, kucoinGo.ErrNonExistingMarket, history.Err())
+
+	deals := kucoin.IterMergedDealtOrders("", "", time.Time{}, time.Time{})
+	for deals.Next(ctx) {
+		t.Logf("IterMergedDealtOrders : %#v\n", deals.Item())
+	}
+	require.NoError(t, deals.Err(), defaultErrorMessage)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	deals = kucoin.IterMergedDealtOrders("", "", time.Time{}, time.Time{})
+	require.False(t, deals.Next(cancelled))
+	require.Equal(t, context.Canceled, deals.Err())
+}
+
 func TestOrderDetails(t *testing.T) {
 	_, err := kucoin.OrderDetails("", "", "", 0, 0)
 	if assert.Error(t, err) {
@@ -315,6 +537,34 @@ func TestCreateWithdrawalApply(t *testing.T) {
 	require.NoError(t, err, defaultErrorMessage)
 }
 
+func TestPreflightWithdrawal(t *testing.T) {
+	_, err := kucoin.PreflightWithdrawal("", "", 0)
+	if assert.Error(t, err) {
+		require.Equal(t, kucoinGo.ErrAllParamsRequired, err)
+	}
+	_, err = kucoin.PreflightWithdrawal("BTC", "5969ddc96732d54312eb960e", 1e-9)
+	if assert.Error(t, err) {
+		require.Equal(t, kucoinGo.ErrWithdrawBelowMin, err)
+	}
+
+	allowlisted := kucoinGo.New(apiKey, apiSecret, kucoinGo.WithWithdrawalAllowlist(map[string][]string{
+		"btc": {"5969ddc96732d54312eb960e"},
+	}))
+	_, err = allowlisted.PreflightWithdrawal("BTC", "1BoatSLRHtKNngkdXEeobR76b53LETtpyT", 1)
+	if assert.Error(t, err) {
+		require.Equal(t, kucoinGo.ErrAddressNotAllowed, err)
+	}
+	_, err = allowlisted.PreflightWithdrawal("KCS", "5969ddc96732d54312eb960e", 1)
+	if assert.Error(t, err) {
+		require.Equal(t, kucoinGo.ErrAddressNotAllowed, err)
+	}
+
+	withdrawal, err := allowlisted.PreflightWithdrawal("BTC", "5969ddc96732d54312eb960e", 1)
+	t.Logf("PreflightWithdrawal : %#v\n", withdrawal)
+	require.NoError(t, err, defaultErrorMessage)
+	require.InDelta(t, 1, withdrawal.NetAmount+withdrawal.Fee, 1e-12)
+}
+
 func TestCancelWithdrawal(t *testing.T) {
 	_, err := kucoin.CancelWithdrawal("", "")
 	if assert.Error(t, err) {
@@ -365,3 +615,791 @@ func TestCancelAllOrders(t *testing.T) {
 	err = kucoin.CancelAllOrders("KCS-BTC", "BUY")
 	require.NoError(t, err, defaultErrorMessage)
 }
+
+func TestDryRun(t *testing.T) {
+	dryRun := kucoinGo.New(apiKey, apiSecret, kucoinGo.WithDryRun())
+
+	orderOid, err := dryRun.CreateOrder("KCS-BTC", "BUY", 0.0001700, 1.5)
+	require.NoError(t, err, defaultErrorMessage)
+	require.True(t, strings.HasPrefix(orderOid, "dry-run-"))
+
+	err = dryRun.CancelOrder("KCS-BTC", orderOid, "BUY")
+	require.NoError(t, err, defaultErrorMessage)
+	err = dryRun.CancelAllOrders("KCS-BTC", "")
+	require.NoError(t, err, defaultErrorMessage)
+	withdrawal, err := dryRun.CreateWithdrawalApply("BTC", "5969ddc96732d54312eb960e", 1)
+	require.NoError(t, err, defaultErrorMessage)
+	require.True(t, strings.HasPrefix(withdrawal.TxOid, "dry-run-"))
+	_, err = dryRun.CancelWithdrawal("BTC", withdrawal.TxOid)
+	require.NoError(t, err, defaultErrorMessage)
+}
+
+func TestParseSide(t *testing.T) {
+	side, err := kucoinGo.ParseSide("buy")
+	require.NoError(t, err)
+	require.Equal(t, kucoinGo.Buy, side)
+	require.Equal(t, kucoinGo.Sell, side.Opposite())
+	_, err = kucoinGo.ParseSide("TEST")
+	if assert.Error(t, err) {
+		require.Equal(t, errors.New("Entered invalid parameter. Accepted values: [BUY,SELL]"), err)
+	}
+
+	var trade kucoinGo.Trade
+	require.NoError(t, json.Unmarshal([]byte(`{"direction":"sell"}`), &trade))
+	require.Equal(t, kucoinGo.Sell, trade.Direction)
+
+	status, err := kucoinGo.ParseWalletStatus("cancel")
+	require.NoError(t, err)
+	require.Equal(t, kucoinGo.Canceled, status)
+}
+
+func TestMillis(t *testing.T) {
+	var deal kucoinGo.MergedDeal
+	require.NoError(t, json.Unmarshal([]byte(`{"createdAt":1512046451000}`), &deal))
+	require.Equal(t, time.Date(2017, 11, 30, 12, 54, 11, 0, time.UTC), deal.CreatedAt.UTC())
+	require.Equal(t, int64(1512046451000), deal.CreatedAt.Millis())
+
+	b, err := json.Marshal(deal.CreatedAt)
+	require.NoError(t, err)
+	require.Equal(t, "1512046451000", string(b))
+
+	require.NoError(t, json.Unmarshal([]byte(`{"createdAt":null}`), &deal))
+	require.True(t, deal.CreatedAt.IsZero())
+	b, err = json.Marshal(deal.CreatedAt)
+	require.NoError(t, err)
+	require.Equal(t, "0", string(b))
+}
+
+func TestWalletWatcher(t *testing.T) {
+	watcher := kucoinGo.NewWalletWatcher(kucoin, kucoinGo.WalletWatcherConfig{
+		Coins:    []string{"BTC"},
+		Backfill: true,
+	})
+	events, err := watcher.Poll()
+	t.Logf("WalletWatcher : %#v\n", events)
+	require.NoError(t, err, defaultErrorMessage)
+
+	// Unchanged records aren't emitted again.
+	events, err = watcher.Poll()
+	require.NoError(t, err, defaultErrorMessage)
+	require.Empty(t, events)
+}
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	dryRun := kucoinGo.New(apiKey, apiSecret, kucoinGo.WithDryRun(), kucoinGo.WithTracerProvider(tp))
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "strategy")
+	_, err := dryRun.WithContext(ctx).CancelWithdrawal("BTC", "5969ddc96732d54312eb960e")
+	require.NoError(t, err, defaultErrorMessage)
+	parent.End()
+
+	var span sdktrace.ReadOnlySpan
+	for _, s := range recorder.Ended() {
+		if s.Name() == "POST account/:coin/withdraw/cancel" {
+			span = s
+		}
+	}
+	require.NotNil(t, span)
+	require.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+	require.Contains(t, span.Attributes(), kucoinGo.AttrCoin.String("BTC"))
+	require.Contains(t, span.Attributes(), kucoinGo.AttrEndpoint.String("account/:coin/withdraw/cancel"))
+
+	// Requests are cancelled with their context.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = dryRun.WithContext(ctx).GetSymbols()
+	require.ErrorIs(t, err, context.Canceled)
+	spans := recorder.Ended()
+	require.Equal(t, codes.Error, spans[len(spans)-1].Status().Code)
+}
+
+type roundTripFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestMiddleware(t *testing.T) {
+	var sent []*http.Request
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		sent = append(sent, r)
+		// The open markets are cached by the package: list those of the
+		// other stubs.
+		status, body := 200, `{"success":true,"code":"OK","data":["BTC","ETH","KCS","NEO"]}`
+		if len(sent) == 1 {
+			status, body = 503, `{"success":false,"code":"ERROR","msg":"Unavailable"}`
+		}
+		return &http.Response{
+			StatusCode: status,
+			Status:     http.StatusText(status),
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Header:     make(http.Header),
+		}, nil
+	})
+	var seen []string
+	audit := func(next kucoinGo.Handler) kucoinGo.Handler {
+		return func(req *kucoinGo.Request) (*kucoinGo.Response, error) {
+			seen = append(seen, req.Method+" "+req.Endpoint())
+			return next(req)
+		}
+	}
+	k := kucoinGo.NewCustomClient(testKey, testSecret, http.Client{Transport: transport},
+		kucoinGo.WithRetry(3, time.Millisecond),
+		kucoinGo.WithRateLimit(1000, 10),
+		kucoinGo.WithMiddleware(audit),
+	)
+	k.SetDebug(false)
+
+	markets, err := k.GetOpenMarkets()
+	require.NoError(t, err, defaultErrorMessage)
+	require.Equal(t, []string{"BTC", "ETH", "KCS", "NEO"}, markets)
+	require.Len(t, sent, 2, "the 503 should be retried")
+	require.Equal(t, []string{"GET open/markets"}, seen)
+	require.Empty(t, sent[1].Header.Get("KC-API-SIGNATURE"))
+
+	_, err = k.GetCoinBalance("BTC")
+	last := sent[len(sent)-1]
+	require.Equal(t, "GET account/:coin/balance", seen[len(seen)-1])
+	require.Equal(t, testKey, last.Header.Get("KC-API-KEY"))
+	require.NotEmpty(t, last.Header.Get("KC-API-SIGNATURE"))
+
+	_, err = kucoinGo.New("", "", kucoinGo.WithRetry(3, time.Millisecond)).GetCoinBalance("BTC")
+	require.Equal(t, kucoinGo.ErrAPIKeyRequired, err)
+
+	// Rates of 0 or less don't limit.
+	k = kucoinGo.NewCustomClient(testKey, testSecret, http.Client{Transport: transport}, kucoinGo.WithRateLimit(0, 1))
+	k.SetDebug(false)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err = k.GetOpenMarkets()
+		require.NoError(t, err, defaultErrorMessage)
+	}
+	require.Less(t, time.Since(start), time.Second)
+
+	// Failed calls are dumped once, after their last attempt, without keys.
+	var logs strings.Builder
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+	k = kucoinGo.NewCustomClient(testKey, testSecret, http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: 503, Status: "503 Service Unavailable", Body: io.NopCloser(strings.NewReader("{}"))}, nil
+	})}, kucoinGo.WithRetry(3, time.Millisecond))
+	_, err = k.GetCoinBalance("BTC")
+	require.Error(t, err)
+	require.Empty(t, logs.String(), "debug is off by default")
+	k.SetDebug(true)
+	_, err = k.GetCoinBalance("BTC")
+	require.Error(t, err)
+	require.Equal(t, 1, strings.Count(logs.String(), "dumpReq ok"))
+	require.Contains(t, logs.String(), "Kc-Api-Key: [redacted]")
+	require.Contains(t, logs.String(), "Kc-Api-Signature: [redacted]")
+	require.NotContains(t, logs.String(), testKey)
+
+	// So are the requests not sent in dry run.
+	logs.Reset()
+	orders, _ := orderTransport(`{}`)
+	k = kucoinGo.NewCustomClient(testKey, testSecret, http.Client{Transport: orders}, kucoinGo.WithDryRun())
+	_, err = k.CreateOrder("KCS-BTC", kucoinGo.Buy, 0.0001700, 1.5)
+	require.NoError(t, err, defaultErrorMessage)
+	require.Empty(t, logs.String(), "debug is off by default")
+	k.SetDebug(true)
+	_, err = k.CreateOrder("KCS-BTC", kucoinGo.Buy, 0.0001700, 1.5)
+	require.NoError(t, err, defaultErrorMessage)
+	require.Contains(t, logs.String(), "dry run, request not sent")
+	require.Contains(t, logs.String(), "Kc-Api-Signature: [redacted]")
+	require.NotContains(t, logs.String(), testKey)
+}
+
+func TestCache(t *testing.T) {
+	var mu sync.Mutex
+	var sent int
+	release := make(chan struct{})
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		<-release
+		mu.Lock()
+		sent++
+		body := fmt.Sprintf(`{"success":true,"code":"OK","data":["BTC%d"]}`, sent)
+		mu.Unlock()
+		return &http.Response{StatusCode: 200, Status: "200 OK", Body: io.NopCloser(strings.NewReader(body))}, nil
+	})
+	sentRequests := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return sent
+	}
+	cache := kucoinGo.NewCache(kucoinGo.CacheConfig{
+		TTL:   map[string]time.Duration{"open/markets": 50 * time.Millisecond},
+		Stale: time.Hour,
+	})
+	k1 := kucoinGo.NewCustomClient(testKey, testSecret, http.Client{Transport: transport}, kucoinGo.WithCache(cache))
+	k2 := kucoinGo.NewCustomClient(testKey, testSecret, http.Client{Transport: transport}, kucoinGo.WithCache(cache))
+
+	// Concurrent identical requests are sent once.
+	var wg sync.WaitGroup
+	for _, k := range []*kucoinGo.Kucoin{k1, k2, k1} {
+		wg.Add(1)
+		go func(k *kucoinGo.Kucoin) {
+			defer wg.Done()
+			markets, err := k.GetOpenMarkets()
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"BTC1"}, markets)
+		}(k)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	require.Equal(t, 1, sentRequests())
+
+	markets, err := k2.GetOpenMarkets()
+	require.NoError(t, err)
+	require.Equal(t, []string{"BTC1"}, markets)
+	require.Equal(t, 1, sentRequests())
+
+	// Expired responses are returned while refreshed.
+	time.Sleep(60 * time.Millisecond)
+	markets, err = k1.GetOpenMarkets()
+	require.NoError(t, err)
+	require.Equal(t, []string{"BTC1"}, markets)
+	require.Eventually(t, func() bool { return sentRequests() == 2 }, time.Second, time.Millisecond)
+	require.Eventually(t, func() bool {
+		markets, _ := k1.GetOpenMarkets()
+		return len(markets) == 1 && markets[0] == "BTC2"
+	}, time.Second, time.Millisecond)
+
+	cache.Invalidate("open/markets")
+	markets, err = k1.GetOpenMarkets()
+	require.NoError(t, err)
+	require.Equal(t, []string{"BTC3"}, markets)
+}
+
+func TestCacheCancelledLeader(t *testing.T) {
+	var sent atomic.Int32
+	received := make(chan struct{}, 1)
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if sent.Add(1) == 1 {
+			received <- struct{}{}
+			<-r.Context().Done()
+			return nil, r.Context().Err()
+		}
+		body := `{"success":true,"code":"OK","data":["BTC"]}`
+		return &http.Response{StatusCode: 200, Status: "200 OK", Body: io.NopCloser(strings.NewReader(body))}, nil
+	})
+	cache := kucoinGo.NewCache(kucoinGo.CacheConfig{})
+	k := kucoinGo.NewCustomClient(testKey, testSecret, http.Client{Transport: transport}, kucoinGo.WithCache(cache))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error)
+	go func() {
+		_, err := k.WithContext(ctx).GetOpenMarkets()
+		leader <- err
+	}()
+	<-received
+
+	// Waiters give up with their own context.
+	timeout, cancelTimeout := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelTimeout()
+	_, err := k.WithContext(timeout).GetOpenMarkets()
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// They don't share the error of a cancelled leader but send the request.
+	waiter := make(chan error)
+	go func() {
+		markets, err := k.GetOpenMarkets()
+		assert.Equal(t, []string{"BTC"}, markets)
+		waiter <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	require.ErrorIs(t, <-leader, context.Canceled)
+	require.NoError(t, <-waiter)
+	require.Equal(t, int32(2), sent.Load())
+}
+
+func TestAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	auditLog, err := kucoinGo.OpenAuditLog(path)
+	require.NoError(t, err)
+	k := kucoinGo.New(apiKey, apiSecret, kucoinGo.WithDryRun(), kucoinGo.WithAuditSink(auditLog))
+
+	_, err = k.CreateOrder("", kucoinGo.Buy, 0.0001700, 1.5)
+	require.Equal(t, kucoinGo.ErrAllParamsRequired, err)
+	_, err = k.CancelWithdrawal("BTC", "")
+	require.Equal(t, kucoinGo.ErrAllParamsRequired, err)
+	require.NoError(t, auditLog.Close())
+
+	// Reopened logs continue the chain.
+	auditLog, err = kucoinGo.OpenAuditLog(path)
+	require.NoError(t, err)
+	k = kucoinGo.New(apiKey, apiSecret, kucoinGo.WithAuditSink(auditLog))
+	require.Equal(t, kucoinGo.ErrSymbolRequired, k.CancelAllOrders("", ""))
+	require.NoError(t, auditLog.Close())
+
+	n, err := kucoinGo.VerifyAuditLog(path)
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	var line struct{ Record kucoinGo.AuditRecord }
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &line))
+	require.Equal(t, "CreateOrder", line.Record.Action)
+	require.Equal(t, map[string]string{"symbol": "", "side": "BUY", "price": "0.00017", "amount": "1.5"}, line.Record.Params)
+	require.False(t, line.Record.Success)
+	require.True(t, line.Record.DryRun)
+	require.Equal(t, kucoinGo.ErrAllParamsRequired.Error(), line.Record.Error)
+
+	// Removing a record breaks the chain.
+	require.NoError(t, os.WriteFile(path, []byte(lines[0]+"\n"+lines[2]+"\n"), 0600))
+	_, err = kucoinGo.VerifyAuditLog(path)
+	require.ErrorIs(t, err, kucoinGo.ErrAuditLogTampered)
+	_, err = kucoinGo.OpenAuditLog(path)
+	require.ErrorIs(t, err, kucoinGo.ErrAuditLogTampered)
+}
+
+func TestCredentials(t *testing.T) {
+	dir := t.TempDir()
+	var signedWith []string
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		signedWith = append(signedWith, r.Header.Get("KC-API-KEY"))
+		return &http.Response{
+			StatusCode: 200,
+			Status:     "200 OK",
+			Body:       io.NopCloser(strings.NewReader(`{"success":true,"code":"OK","data":{}}`)),
+		}, nil
+	})
+
+	// Files must not be readable by others.
+	path := filepath.Join(dir, "kucoin.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"apiKey":"file-key","apiSecret":"file-secret"}`), 0644))
+	_, _, err := kucoinGo.FileCredentials(path).Credentials()
+	require.ErrorIs(t, err, kucoinGo.ErrCredentialsFileMode)
+	require.NoError(t, os.Chmod(path, 0600))
+	key, secret, err := kucoinGo.FileCredentials(path).Credentials()
+	require.NoError(t, err)
+	require.Equal(t, []string{"file-key", "file-secret"}, []string{key, secret})
+
+	t.Setenv("TEST_KUCOIN_KEY", "env-key")
+	t.Setenv("TEST_KUCOIN_SECRET", "env-secret")
+	key, _, err = kucoinGo.EnvCredentials("TEST_KUCOIN_KEY", "TEST_KUCOIN_SECRET").Credentials()
+	require.NoError(t, err)
+	require.Equal(t, "env-key", key)
+
+	// Keystores are decrypted with their passphrase and reloaded when rewritten.
+	path = filepath.Join(dir, "kucoin.keystore")
+	require.NoError(t, kucoinGo.WriteKeystore(path, "passphrase", "old-key", "old-secret"))
+	_, err = kucoinGo.KeystoreCredentials(path, "wrong")
+	require.ErrorIs(t, err, kucoinGo.ErrKeystorePassphrase)
+	creds, err := kucoinGo.KeystoreCredentials(path, "passphrase")
+	require.NoError(t, err)
+	k := kucoinGo.NewWithCredentials(creds, kucoinGo.WithHTTPClient(http.Client{Transport: transport}))
+	_, err = k.GetUserInfo()
+	require.NoError(t, err)
+	require.NoError(t, kucoinGo.WriteKeystore(path, "passphrase", "new-key", "new-secret"))
+	_, err = k.GetUserInfo()
+	require.NoError(t, err)
+	require.Equal(t, []string{"old-key", "new-key"}, signedWith)
+
+	k = kucoinGo.NewWithCredentials(kucoinGo.NewStaticCredentials("", ""))
+	_, err = k.GetUserInfo()
+	require.Equal(t, kucoinGo.ErrAPIKeyRequired, err)
+
+	static := kucoinGo.NewStaticCredentials("static-key", "static-secret")
+	static.Rotate("rotated-key", "rotated-secret")
+	key, _, err = static.Credentials()
+	require.NoError(t, err)
+	require.Equal(t, "rotated-key", key)
+}
+
+func TestAccountPool(t *testing.T) {
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		status, body := 200, `{"success":true,"code":"OK","data":{"datas":[{"coinType":"BTC","balance":1,"freezeBalance":0.5},{"coinType":"KCS","balance":10}],"pageNos":1}}`
+		if r.Header.Get("KC-API-KEY") == "broken-key" {
+			status, body = 401, `{"success":false,"code":"UNAUTH","msg":"Invalid key"}`
+		}
+		return &http.Response{StatusCode: status, Status: http.StatusText(status), Body: io.NopCloser(strings.NewReader(body))}, nil
+	})
+	httpClient := kucoinGo.WithHTTPClient(http.Client{Transport: transport})
+	pool := kucoinGo.NewAccountPool(nil, httpClient)
+	for _, name := range []string{"desk-a", "desk-b"} {
+		_, err := pool.Add(kucoinGo.Account{Name: name, Credentials: kucoinGo.NewStaticCredentials(name, testSecret), RateLimit: 10})
+		require.NoError(t, err)
+	}
+	_, err := pool.Add(kucoinGo.Account{Name: "desk-a", Credentials: kucoinGo.NewStaticCredentials("desk-a", testSecret)})
+	require.ErrorIs(t, err, kucoinGo.ErrAccountExists)
+	require.Equal(t, []string{"desk-a", "desk-b"}, pool.Names())
+
+	balances, err := pool.Balances(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, kucoinGo.CoinBalance{CoinType: "BTC", Balance: 2, FreezeBalance: 1}, balances.Totals["BTC"])
+	require.Equal(t, 20.0, balances.Totals["KCS"].Balance)
+	require.Len(t, balances.Accounts["desk-b"], 2)
+
+	// Failing accounts don't hide the others.
+	broken, err := pool.Add(kucoinGo.Account{Name: "broken", Credentials: kucoinGo.NewStaticCredentials("broken-key", testSecret)})
+	require.NoError(t, err)
+	broken.SetDebug(false)
+	balances, err = pool.Balances(context.Background())
+	require.Error(t, err)
+	require.True(t, strings.HasPrefix(err.Error(), "broken: "))
+	require.Len(t, balances.Accounts, 2)
+	pool.Remove("broken")
+
+	_, err = pool.CreateOrder(context.Background(), "desk-c", "KCS-BTC", kucoinGo.Buy, 0.0001700, 1.5)
+	require.ErrorIs(t, err, kucoinGo.ErrUnknownAccount)
+}
+
+// orderTransport stubs order creation, details and cancellation, details
+// being the data of the order details, which fail if empty, and the book
+// selling 10 KCS at 0.0002 BTC. cancelled returns the oids of the cancelled
+// orders.
+func orderTransport(details string) (transport http.RoundTripper, cancelled func() []string) {
+	var mu sync.Mutex
+	var oids []string
+	transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		status, body := 200, `{"success":true,"code":"OK","data":{}}`
+		switch path.Base(r.URL.Path) {
+		case "coins-trending":
+			body = `{"success":true,"code":"OK","data":[{"coinPair":"KCS-BTC"}]}`
+		case "order":
+			body = `{"success":true,"code":"OK","data":{"orderOid":"oid-1"}}`
+		case "orders":
+			body = `{"success":true,"code":"OK","data":{"SELL":[[0.0002,10,0.002]],"BUY":[]}}`
+		case "detail":
+			body = `{"success":true,"code":"OK","data":` + details + `}`
+			if len(details) < 1 {
+				status, body = 503, `{"success":false,"code":"ERROR","msg":"Unavailable"}`
+			}
+		case "cancel-order":
+			r.ParseForm()
+			mu.Lock()
+			oids = append(oids, r.PostForm.Get("orderOid"))
+			mu.Unlock()
+		}
+		return &http.Response{StatusCode: status, Status: http.StatusText(status), Body: io.NopCloser(strings.NewReader(body))}, nil
+	})
+	return transport, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), oids...)
+	}
+}
+
+func TestCreateOrderWithOptionsDetailsFailure(t *testing.T) {
+	transport, cancelled := orderTransport("")
+	k := kucoinGo.NewCustomClient(testKey, testSecret, http.Client{Transport: transport})
+	k.SetDebug(false)
+
+	// IOC orders whose remainder can't be checked are cancelled.
+	_, err := k.CreateOrderWithOptions("KCS-BTC", kucoinGo.Buy, 0.0001700, 1.5, kucoinGo.OrderOptions{TimeInForce: kucoinGo.IOC})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), http.StatusText(503))
+	require.Equal(t, []string{"oid-1"}, cancelled())
+}
+
+func TestGTTOutlivesContext(t *testing.T) {
+	transport, cancelled := orderTransport(`{}`)
+	k := kucoinGo.NewCustomClient(testKey, testSecret, http.Client{Transport: transport})
+	k.SetDebug(false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := k.WithContext(ctx).CreateOrderWithOptions("KCS-BTC", kucoinGo.Buy, 0.0001700, 1.5,
+		kucoinGo.OrderOptions{TimeInForce: kucoinGo.GTT, ExpireAfter: 20 * time.Millisecond})
+	require.NoError(t, err)
+	cancel()
+	require.Eventually(t, func() bool {
+		return len(cancelled()) == 1 && cancelled()[0] == "oid-1"
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestWalletWatcherStub(t *testing.T) {
+	var mu sync.Mutex
+	// pages holds the withdrawal pages by status. When set, block delays the
+	// requests, signalled on entered.
+	pages := map[string][]string{"PENDING": {`{"oid":"w-1","type":"WITHDRAW","status":"PENDING","updatedAt":1}`}}
+	var block, entered chan struct{}
+	var requested []string
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		// The open markets are cached by the package: list those of the
+		// other stubs.
+		body := `{"success":true,"code":"OK","data":["BTC","ETH","KCS","NEO"]}`
+		switch {
+		case strings.HasSuffix(r.URL.Path, "coin-info"):
+			body = `{"success":true,"code":"OK","data":{"coin":"BTC","confirmationCount":2}}`
+		case strings.HasSuffix(r.URL.Path, "wallet/records"):
+			mu.Lock()
+			wait, in := block, entered
+			data, n := "", 0
+			if q := r.URL.Query(); q.Get("type") == "WITHDRAW" {
+				page, _ := strconv.Atoi(q.Get("page"))
+				requested = append(requested, q.Get("status")+"/"+q.Get("page"))
+				n = len(pages[q.Get("status")])
+				if page > 0 && page <= n {
+					data = pages[q.Get("status")][page-1]
+				}
+			}
+			mu.Unlock()
+			if wait != nil {
+				select {
+				case in <- struct{}{}:
+				default:
+				}
+				<-wait
+			}
+			body = fmt.Sprintf(`{"success":true,"code":"OK","data":{"datas":[%s],"pageNos":%d}}`, data, n)
+		}
+		return &http.Response{StatusCode: 200, Status: "200 OK", Body: io.NopCloser(strings.NewReader(body))}, nil
+	})
+	setPages := func(p map[string][]string) []string {
+		mu.Lock()
+		defer mu.Unlock()
+		pages = p
+		r := requested
+		requested = nil
+		return r
+	}
+	k := kucoinGo.NewCustomClient(testKey, testSecret, http.Client{Transport: transport})
+	watcher := kucoinGo.NewWalletWatcher(k, kucoinGo.WalletWatcherConfig{Coins: []string{"BTC"}, Interval: time.Millisecond})
+
+	events, err := watcher.Poll()
+	require.NoError(t, err, defaultErrorMessage)
+	require.Empty(t, events)
+
+	setPages(map[string][]string{"FINISHED": {`{"oid":"w-1","type":"WITHDRAW","status":"FINISHED","updatedAt":2}`}})
+	events, err = watcher.Poll()
+	require.NoError(t, err, defaultErrorMessage)
+	require.Len(t, events, 1)
+	require.Equal(t, kucoinGo.Finished, events[0].Status)
+	require.Equal(t, 2, events[0].Confirmations)
+
+	// Final records are emitted once, while on the page and once gone.
+	for _, p := range []map[string][]string{{"FINISHED": {`{"oid":"w-1","type":"WITHDRAW","status":"FINISHED","updatedAt":2}`}}, {}} {
+		setPages(p)
+		events, err = watcher.Poll()
+		require.NoError(t, err, defaultErrorMessage)
+		require.Empty(t, events)
+	}
+
+	// Statuses are paged until a known record, here w-2 on the second page.
+	setPages(map[string][]string{"FINISHED": {`{"oid":"w-2","type":"WITHDRAW","status":"FINISHED","updatedAt":3}`}})
+	_, err = watcher.Poll()
+	require.NoError(t, err, defaultErrorMessage)
+	setPages(map[string][]string{"FINISHED": {
+		`{"oid":"w-4","type":"WITHDRAW","status":"FINISHED","updatedAt":5}`,
+		`{"oid":"w-3","type":"WITHDRAW","status":"FINISHED","updatedAt":4},{"oid":"w-2","type":"WITHDRAW","status":"FINISHED","updatedAt":3}`,
+		`{"oid":"w-1","type":"WITHDRAW","status":"FINISHED","updatedAt":2}`,
+	}})
+	events, err = watcher.Poll()
+	require.NoError(t, err, defaultErrorMessage)
+	require.Len(t, events, 2)
+	require.Equal(t, "w-3", events[0].Record.Oid)
+	require.Equal(t, "w-4", events[1].Record.Oid)
+	require.Equal(t, []string{"PENDING/1", "FINISHED/1", "FINISHED/2", "CANCEL/1"}, setPages(nil))
+
+	// Concurrent polls don't wait for the running one.
+	unblock, in := make(chan struct{}), make(chan struct{}, 1)
+	mu.Lock()
+	block, entered = unblock, in
+	mu.Unlock()
+	polled := make(chan error)
+	go func() {
+		_, err := watcher.Poll()
+		polled <- err
+	}()
+	<-in
+	_, err = watcher.Poll()
+	require.Equal(t, kucoinGo.ErrWalletPollInProgress, err)
+	close(unblock)
+	require.NoError(t, <-polled, defaultErrorMessage)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.Equal(t, context.Canceled, watcher.Run(ctx))
+	require.Equal(t, kucoinGo.ErrWalletWatcherStarted, watcher.Run(ctx))
+	_, ok := <-watcher.Events()
+	require.False(t, ok)
+}
+
+// riskTransport stubs the market and account data checked by RiskGuard: KCS
+// trades at 0.0002 BTC with one active order of 10 KCS, the account holds 5
+// KCS of which 1 is frozen, and 0.01 BTC. calls returns the endpoints called,
+// with the symbol for order/cancel-all.
+func riskTransport() (transport http.RoundTripper, calls func() []string) {
+	var mu sync.Mutex
+	var endpoints []string
+	transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		endpoint := strings.TrimPrefix(r.URL.Path, "/v1/")
+		data := `{}`
+		switch p := r.URL.Path; {
+		case strings.HasSuffix(p, "coins-trending"):
+			data = `[{"coinPair":"KCS-BTC"},{"coinPair":"ETH-BTC"},{"coinPair":"NEO-ETH"}]`
+		case strings.HasSuffix(p, "open/markets"):
+			data = `["BTC","ETH","KCS","NEO"]`
+		case strings.HasSuffix(p, "open/tick"):
+			data = `{"coinType":"KCS","coinTypePair":"BTC","lastDealPrice":0.0002}`
+		case strings.HasSuffix(p, "order/active-map"):
+			data = `{"BUY":[{"oid":"o-1","price":0.0002,"pendingAmount":10}],"SELL":[]}`
+		case strings.HasSuffix(p, "account/BTC/balance"):
+			data = `{"coinType":"BTC","balance":0.01}`
+		case strings.HasSuffix(p, "account/KCS/balance"):
+			data = `{"coinType":"KCS","balance":4,"freezeBalance":1}`
+		case strings.HasSuffix(p, "account/balances"):
+			data = `{"datas":[{"coinType":"KCS","balance":4,"freezeBalance":1},{"coinType":"BTC","balance":0.01}],"pageNos":1}`
+		case strings.HasSuffix(p, "order/cancel-all"):
+			r.ParseForm()
+			endpoint += " " + r.PostForm.Get("symbol")
+		case strings.HasSuffix(p, "order"):
+			data = `{"orderOid":"oid-1"}`
+		}
+		mu.Lock()
+		endpoints = append(endpoints, endpoint)
+		mu.Unlock()
+		body := `{"success":true,"code":"OK","data":` + data + `}`
+		return &http.Response{StatusCode: 200, Status: "200 OK", Body: io.NopCloser(strings.NewReader(body))}, nil
+	})
+	return transport, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), endpoints...)
+	}
+}
+
+// filterCalls returns the calls starting with prefix.
+func filterCalls(calls []string, prefix string) (filtered []string) {
+	for _, call := range calls {
+		if strings.HasPrefix(call, prefix) {
+			filtered = append(filtered, call)
+		}
+	}
+	return
+}
+
+func TestRiskGuardKill(t *testing.T) {
+	transport, calls := riskTransport()
+	guard := kucoinGo.NewRiskGuard(kucoinGo.NewCustomClient(testKey, testSecret, http.Client{Transport: transport}), kucoinGo.RiskLimits{})
+
+	// Orders placed without the guard are found by their frozen balance.
+	require.NoError(t, guard.Kill())
+	cancelled := filterCalls(calls(), "order/cancel-all")
+	require.Contains(t, cancelled, "order/cancel-all KCS-BTC")
+	for _, call := range cancelled {
+		require.Contains(t, call, "KCS")
+	}
+}
+
+func TestRiskGuardConcurrency(t *testing.T) {
+	transport, calls := riskTransport()
+	guard := kucoinGo.NewRiskGuard(kucoinGo.NewCustomClient(testKey, testSecret, http.Client{Transport: transport}), kucoinGo.RiskLimits{
+		MaxOpenOrders:     100,
+		MaxPriceDeviation: 0.5,
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := guard.CreateOrder("KCS-BTC", kucoinGo.Buy, 0.0002, 1)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	// Every check sees the orders placed before it, and the price is reused.
+	orders := filterCalls(calls(), "order")
+	require.Len(t, orders, 16)
+	for i := 0; i < len(orders); i += 2 {
+		require.Equal(t, []string{"order/active-map", "order"}, orders[i:i+2])
+	}
+	require.Len(t, filterCalls(calls(), "open/tick"), 1)
+}
+
+func TestRiskGuardRules(t *testing.T) {
+	transport, _ := riskTransport()
+	k := kucoinGo.NewCustomClient(testKey, testSecret, http.Client{Transport: transport})
+	riskError := func(err error) *kucoinGo.RiskError {
+		t.Helper()
+		var riskErr *kucoinGo.RiskError
+		require.ErrorAs(t, err, &riskErr)
+		return riskErr
+	}
+
+	guard := kucoinGo.NewRiskGuard(k, kucoinGo.RiskLimits{MaxPriceDeviation: 0.1})
+	_, err := guard.CreateOrder("KCS-BTC", kucoinGo.Buy, 0.00025, 1)
+	rerr := riskError(err)
+	require.Equal(t, kucoinGo.RulePriceBand, rerr.Rule)
+	require.InDelta(t, 0.25, rerr.Value, 1e-9)
+	_, err = guard.CreateOrder("KCS-BTC", kucoinGo.Buy, 0.00021, 1)
+	require.NoError(t, err, defaultErrorMessage)
+
+	guard = kucoinGo.NewRiskGuard(k, kucoinGo.RiskLimits{MaxOpenOrders: 1})
+	_, err = guard.CreateOrder("KCS-BTC", kucoinGo.Buy, 0.0002, 1)
+	require.Equal(t, &kucoinGo.RiskError{Rule: kucoinGo.RuleOpenOrders, Limit: 1, Value: 2}, err)
+
+	// The active order of 10 KCS at 0.0002 BTC counts for 0.002 BTC.
+	guard = kucoinGo.NewRiskGuard(k, kucoinGo.RiskLimits{MaxSymbolNotional: 0.0025})
+	_, err = guard.CreateOrder("KCS-BTC", kucoinGo.Buy, 0.0002, 5)
+	rerr = riskError(err)
+	require.Equal(t, kucoinGo.RuleSymbolNotional, rerr.Rule)
+	require.InDelta(t, 0.003, rerr.Value, 1e-9)
+	_, err = guard.CreateOrder("KCS-BTC", kucoinGo.Buy, 0.0002, 2)
+	require.NoError(t, err, defaultErrorMessage)
+
+	guard = kucoinGo.NewRiskGuard(k, kucoinGo.RiskLimits{CheckBalance: true})
+	_, err = guard.CreateOrder("KCS-BTC", kucoinGo.Buy, 0.0002, 100)
+	rerr = riskError(err)
+	require.Equal(t, kucoinGo.RuleBalance, rerr.Rule)
+	require.Equal(t, 0.01, rerr.Limit)
+	require.InDelta(t, 0.02, rerr.Value, 1e-9)
+	_, err = guard.CreateOrder("KCS-BTC", kucoinGo.Sell, 0.0002, 5)
+	require.Equal(t, &kucoinGo.RiskError{Rule: kucoinGo.RuleBalance, Limit: 4, Value: 5}, err)
+	_, err = guard.CreateOrder("KCS-BTC", kucoinGo.Sell, 0.0002, 4)
+	require.NoError(t, err, defaultErrorMessage)
+
+	_, err = guard.CreateOrderByString("KCS-BTC", kucoinGo.Buy, "", "1")
+	require.Equal(t, kucoinGo.ErrAllParamsRequired, err)
+	_, err = guard.CreateOrderByString("KCS-BTC", kucoinGo.Buy, "0.0002", "")
+	require.Equal(t, kucoinGo.ErrAllParamsRequired, err)
+}
+
+func TestFillOrKillPartialFill(t *testing.T) {
+	transport, cancelled := orderTransport(`{"dealAmount":0.5,"pendingAmount":1}`)
+	k := kucoinGo.NewCustomClient(testKey, testSecret, http.Client{Transport: transport})
+
+	orderOid, err := k.CreateOrderWithOptions("KCS-BTC", kucoinGo.Buy, 0.0002, 1.5, kucoinGo.OrderOptions{TimeInForce: kucoinGo.FOK})
+	require.Equal(t, &kucoinGo.PartialFillError{OrderOid: "oid-1", Filled: 0.5, Cancelled: 1}, err)
+	require.Equal(t, "oid-1", orderOid)
+	require.Equal(t, []string{"oid-1"}, cancelled())
+
+	transport, _ = orderTransport(`{"dealAmount":0,"pendingAmount":1.5}`)
+	k = kucoinGo.NewCustomClient(testKey, testSecret, http.Client{Transport: transport})
+	_, err = k.CreateOrderWithOptions("KCS-BTC", kucoinGo.Buy, 0.0002, 1.5, kucoinGo.OrderOptions{TimeInForce: kucoinGo.FOK})
+	require.Equal(t, kucoinGo.ErrFillOrKillNotFilled, err)
+}
+
+func TestGTTExpiries(t *testing.T) {
+	transport, cancelled := orderTransport(`{}`)
+	k := kucoinGo.NewCustomClient(testKey, testSecret, http.Client{Transport: transport})
+
+	orderOid, err := k.CreateOrderWithOptions("KCS-BTC", kucoinGo.Buy, 0.0002, 1.5,
+		kucoinGo.OrderOptions{TimeInForce: kucoinGo.GTT, ExpireAfter: time.Hour})
+	require.NoError(t, err, defaultErrorMessage)
+	pending := k.PendingExpiries()
+	require.Len(t, pending, 1)
+	require.Equal(t, kucoinGo.PendingExpiry{OrderOid: orderOid, Symbol: "KCS-BTC", Side: kucoinGo.Buy, At: pending[0].At}, pending[0])
+	require.WithinDuration(t, time.Now().Add(time.Hour), pending[0].At, time.Minute)
+
+	// Stopped expiries can be restored, e.g. by another process.
+	require.Equal(t, pending, k.StopExpiries())
+	require.Empty(t, k.PendingExpiries())
+	require.NoError(t, k.ExpireOrderAt("KCS-BTC", orderOid, kucoinGo.Buy, time.Now().Add(10*time.Millisecond)))
+	require.Eventually(t, func() bool { return len(cancelled()) == 1 }, time.Second, 5*time.Millisecond)
+	require.Empty(t, k.PendingExpiries())
+
+	require.NoError(t, k.ExpireOrderAt("KCS-BTC", orderOid, kucoinGo.Buy, time.Now().Add(time.Hour)))
+	require.NoError(t, k.FlushExpiries())
+	require.Len(t, cancelled(), 2)
+	require.Empty(t, k.PendingExpiries())
+	require.Equal(t, kucoinGo.ErrAllParamsRequired, k.ExpireOrderAt("", orderOid, kucoinGo.Buy, time.Now()))
+}
@@ -0,0 +1,53 @@
+package kucoin
+
+import (
+	"strings"
+	"time"
+)
+
+// Metrics receives measurements of the REST client, set with WithMetrics,
+// and of websocket connections, set with websocket.WebSocket.SetMetrics.
+// Implementations must be safe for concurrent use and must not block.
+type Metrics interface {
+	// Request is called after a REST call with the endpoint, e.g.
+	// account/:coin/balance, the HTTP status, 0 if no response was received,
+	// and the time taken.
+	Request(method, endpoint string, status int, d time.Duration)
+	// AuthFailure is called when a request can't be signed or is rejected
+	// as unauthorized.
+	AuthFailure(endpoint string)
+	// Retry is called before a request is retried.
+	Retry(endpoint string)
+	// RateLimitWait is called when a request waited for the rate limit.
+	RateLimitWait(endpoint string, d time.Duration)
+
+	// Message is called for each data frame received on a topic.
+	Message(topic string)
+	// DecodeError is called for each frame of a topic which failed to decode.
+	DecodeError(topic string)
+	// Resubscribe is called when a topic and symbol is subscribed again by
+	// the same WebSocket, e.g. to replace a lost connection.
+	Resubscribe(topic string)
+	// Ping is called with the round-trip time of a websocket ping.
+	Ping(rtt time.Duration)
+	// Backlog is called with the number of updates waiting in the updates
+	// channel of a topic, after each update is queued.
+	Backlog(topic string, n int)
+}
+
+// WithMetrics makes the client report its requests to m.
+func WithMetrics(m Metrics) Option {
+	return func(c *client) {
+		c.metrics = m
+	}
+}
+
+// endpointOf returns the resource with its coin replaced, so that endpoints
+// can be used as metric labels, e.g. account/:coin/balance.
+func endpointOf(resource string) string {
+	parts := strings.Split(resource, "/")
+	if len(parts) > 2 && parts[0] == "account" {
+		parts[1] = ":coin"
+	}
+	return strings.Join(parts, "/")
+}
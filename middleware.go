@@ -0,0 +1,330 @@
+package kucoin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Request is a REST request going through the middleware chain.
+type Request struct {
+	Context  context.Context
+	Method   string
+	Resource string // e.g. account/BTC/balance
+	Payload  map[string]string
+	Auth     bool // the request must be signed
+	Header   http.Header
+}
+
+// Endpoint returns the resource with its coin replaced, e.g.
+// account/:coin/balance.
+func (r *Request) Endpoint() string {
+	return endpointOf(r.Resource)
+}
+
+// encode returns the URL of the request and its encoded parameters, which
+// are the query string of GET requests and the body of the others.
+func (r *Request) encode() (*url.URL, string, error) {
+	URL, err := url.Parse(kucoinURL)
+	if err != nil {
+		return nil, "", err
+	}
+	URL.Path = path.Join(URL.Path, r.Resource)
+	values := url.Values{}
+	for key, value := range r.Payload {
+		values.Set(key, value)
+	}
+	if r.Method == "GET" {
+		URL.RawQuery = values.Encode()
+	}
+	return URL, values.Encode(), nil
+}
+
+// HTTPRequest returns the HTTP request to send.
+func (r *Request) HTTPRequest() (*http.Request, error) {
+	URL, params, err := r.encode()
+	if err != nil {
+		return nil, err
+	}
+	var req *http.Request
+	if r.Method == "GET" {
+		req, err = http.NewRequestWithContext(r.Context, r.Method, URL.String(), nil)
+	} else {
+		req, err = http.NewRequestWithContext(r.Context, r.Method, URL.String(), strings.NewReader(params))
+	}
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range r.Header {
+		req.Header[key] = append([]string(nil), values...)
+	}
+	if r.Method == "POST" || r.Method == "PUT" {
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded;charset=utf-8")
+	}
+	req.Header.Add("Accept", "application/json")
+	return req, nil
+}
+
+// Response is the response of a REST request. Status is 0 if the request
+// wasn't sent.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Handler handles a request. It may return both a response and an error,
+// e.g. for responses with an HTTP error status.
+type Handler func(req *Request) (*Response, error)
+
+// Middleware wraps a handler to add behaviour around requests, e.g.
+//
+//	func(next kucoin.Handler) kucoin.Handler {
+//		return func(req *kucoin.Request) (*kucoin.Response, error) {
+//			log.Println(req.Method, req.Endpoint())
+//			return next(req)
+//		}
+//	}
+type Middleware func(next Handler) Handler
+
+// WithMiddleware inserts mws in the chain handling requests, the first one
+// outermost. They run once per call, inside the tracing and cache links and
+// before the logging, retry, rate limit, metrics, signer and dry run links,
+// in this order.
+func WithMiddleware(mws ...Middleware) Option {
+	return func(c *client) {
+		c.mws = append(c.mws, mws...)
+	}
+}
+
+// WithRetry makes the client retry GET requests up to attempts times when
+// they fail with a network error, a 429 or a 5xx status, waiting backoff
+// then twice as long before each new attempt.
+func WithRetry(attempts int, backoff time.Duration) Option {
+	return func(c *client) {
+		c.retry = retryPolicy{attempts: attempts, backoff: backoff}
+	}
+}
+
+// WithRateLimit limits the client to perSecond requests, with bursts of
+// burst requests. Requests wait for their turn or their context. A rate of
+// 0 or less means no limit.
+func WithRateLimit(perSecond float64, burst int) Option {
+	return func(c *client) {
+		c.limiter = nil
+		if perSecond > 0 {
+			c.limiter = newRateLimiter(perSecond, burst)
+		}
+	}
+}
+
+// chain builds the handler of the requests.
+func (c *client) chain() Handler {
+	links := []Middleware{c.traceLink, c.cacheLink}
+	links = append(links, c.mws...)
+	links = append(links, c.logLink, c.retryLink, c.rateLimitLink, c.metricsLink, c.signLink, c.dryRunLink)
+	h := Handler(c.send)
+	for i := len(links) - 1; i >= 0; i-- {
+		h = links[i](h)
+	}
+	return h
+}
+
+func (c *client) traceLink(next Handler) Handler {
+	return func(req *Request) (*Response, error) {
+		ctx, span := c.startSpan(req.Context, req.Method, req.Resource, req.Payload)
+		req.Context = ctx
+		resp, err := next(req)
+		if resp != nil {
+			endSpan(span, resp.Status, resp.Body, err)
+		} else {
+			endSpan(span, 0, nil, err)
+		}
+		return resp, err
+	}
+}
+
+type retryPolicy struct {
+	attempts int
+	backoff  time.Duration
+}
+
+func (p retryPolicy) retryable(req *Request, resp *Response, err error) bool {
+	if err == nil || req.Method != "GET" || err == ErrAPIKeyRequired || errors.Is(err, ErrCredentials) || req.Context.Err() != nil {
+		return false
+	}
+	return resp == nil || resp.Status == http.StatusTooManyRequests || resp.Status >= 500
+}
+
+func (c *client) retryLink(next Handler) Handler {
+	return func(req *Request) (*Response, error) {
+		backoff := c.retry.backoff
+		for attempt := 1; ; attempt++ {
+			resp, err := next(req)
+			if attempt >= c.retry.attempts || !c.retry.retryable(req, resp, err) {
+				return resp, err
+			}
+			if c.metrics != nil {
+				c.metrics.Retry(req.Endpoint())
+			}
+			if err := sleepContext(req.Context, backoff); err != nil {
+				return nil, err
+			}
+			backoff *= 2
+		}
+	}
+}
+
+func (c *client) rateLimitLink(next Handler) Handler {
+	return func(req *Request) (*Response, error) {
+		if c.limiter != nil {
+			wait := c.limiter.reserve(time.Now())
+			if wait > 0 {
+				if c.metrics != nil {
+					c.metrics.RateLimitWait(req.Endpoint(), wait)
+				}
+				if err := sleepContext(req.Context, wait); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return next(req)
+	}
+}
+
+func (c *client) metricsLink(next Handler) Handler {
+	return func(req *Request) (*Response, error) {
+		if c.metrics == nil {
+			return next(req)
+		}
+		start := time.Now()
+		resp, err := next(req)
+		status := 0
+		if resp != nil {
+			status = resp.Status
+		}
+		c.metrics.Request(req.Method, req.Endpoint(), status, time.Since(start))
+		return resp, err
+	}
+}
+
+// signLink signs the requests which need it, at each attempt so that their
+// nonce is fresh.
+func (c *client) signLink(next Handler) Handler {
+	return func(req *Request) (*Response, error) {
+		if req.Auth {
+			apiKey, apiSecret, err := c.creds.Credentials()
+			if err != nil {
+				c.authFailure(req.Resource)
+				return nil, fmt.Errorf("%w: %s", ErrCredentials, err)
+			}
+			if len(apiKey) == 0 || len(apiSecret) == 0 {
+				c.authFailure(req.Resource)
+				return nil, ErrAPIKeyRequired
+			}
+			URL, params, err := req.encode()
+			if err != nil {
+				return nil, err
+			}
+			nonce := time.Now().UnixNano() / int64(time.Millisecond)
+			req.Header.Set("KC-API-KEY", apiKey)
+			req.Header.Set("KC-API-NONCE", fmt.Sprintf("%v", nonce))
+			req.Header.Set("KC-API-SIGNATURE", c.sign(apiSecret, URL.Path, params, nonce))
+		}
+		return next(req)
+	}
+}
+
+// logLink dumps the failed calls, once after their last attempt, and their
+// responses in debug mode.
+func (c *client) logLink(next Handler) Handler {
+	return func(req *Request) (*Response, error) {
+		resp, err := next(req)
+		if err != nil && c.debug && !errors.Is(err, context.Canceled) {
+			if r, err := req.HTTPRequest(); err == nil {
+				redactHeader(r.Header)
+				c.dumpRequest(r)
+			}
+			if resp != nil {
+				log.Printf("response: %d %s: %s\n", resp.Status, err, resp.Body)
+			} else {
+				log.Printf("response: %s\n", err)
+			}
+		}
+		return resp, err
+	}
+}
+
+// redactHeader hides the key and signature of the signed requests.
+func redactHeader(h http.Header) {
+	for key := range h {
+		if strings.HasPrefix(http.CanonicalHeaderKey(key), "Kc-Api-") {
+			h[key] = []string{"[redacted]"}
+		}
+	}
+}
+
+func (c *client) dryRunLink(next Handler) Handler {
+	return func(req *Request) (*Response, error) {
+		if !c.dryRun || req.Method == "GET" {
+			return next(req)
+		}
+		r, err := req.HTTPRequest()
+		if err != nil {
+			return nil, err
+		}
+		data, err := c.dryRunResponse(r)
+		if err != nil {
+			return nil, err
+		}
+		return &Response{Body: data}, nil
+	}
+}
+
+// rateLimiter spaces requests evenly, allowing bursts.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	burst    int
+	next     time.Time // theoretical arrival time of the next request
+}
+
+func newRateLimiter(perSecond float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / perSecond), burst: burst}
+}
+
+// reserve reserves a turn and returns how long to wait for it.
+func (l *rateLimiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now) - time.Duration(l.burst-1)*l.interval
+	l.next = l.next.Add(l.interval)
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
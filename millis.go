@@ -0,0 +1,64 @@
+package kucoin
+
+import (
+	"bytes"
+	"strconv"
+	"time"
+)
+
+// Millis is a time encoded by Kucoin in milliseconds from Unix epoch.
+// It decodes into the embedded time.Time, zero and null decode to the zero time.
+type Millis struct {
+	time.Time
+}
+
+// NewMillis returns the Millis of t.
+func NewMillis(t time.Time) Millis {
+	return Millis{t}
+}
+
+// MillisOf returns the Millis of the milliseconds from Unix epoch.
+func MillisOf(ms int64) Millis {
+	if ms == 0 {
+		return Millis{}
+	}
+	return Millis{time.UnixMilli(ms)}
+}
+
+// Millis returns the time in milliseconds from Unix epoch, zero for the zero time.
+func (m Millis) Millis() int64 {
+	return millis(m.Time)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (m Millis) MarshalJSON() ([]byte, error) {
+	return strconv.AppendInt(nil, m.Millis(), 10), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Quoted and floating point
+// numbers are accepted as well.
+func (m *Millis) UnmarshalJSON(b []byte) error {
+	b = bytes.Trim(b, `"`)
+	if len(b) == 0 || string(b) == "null" {
+		*m = Millis{}
+		return nil
+	}
+	ms, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil {
+		f, ferr := strconv.ParseFloat(string(b), 64)
+		if ferr != nil {
+			return err
+		}
+		ms = int64(f)
+	}
+	*m = MillisOf(ms)
+	return nil
+}
+
+// millis returns t in milliseconds from Unix epoch, zero for the zero time.
+func millis(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
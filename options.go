@@ -0,0 +1,45 @@
+package kucoin
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Option configures a Kucoin client.
+type Option func(*client)
+
+// WithDryRun makes the client validate and sign mutating requests
+// (order creation and cancellation, withdrawal apply and cancellation)
+// without sending them. A synthetic successful result is returned and, in
+// debug mode, the request which would have been sent is logged. GET requests
+// are still sent to Kucoin.
+func WithDryRun() Option {
+	return func(c *client) {
+		c.dryRun = true
+	}
+}
+
+// WithWithdrawalAllowlist restricts withdrawals to the addresses listed per
+// coin, e.g. {"BTC": {"1BoatSLRHtKNngkdXEeobR76b53LETtpyT"}}. Withdrawals of
+// coins without listed addresses are rejected with ErrAddressNotAllowed.
+func WithWithdrawalAllowlist(addresses map[string][]string) Option {
+	return func(c *client) {
+		c.allowlist = make(map[string]map[string]struct{})
+		for coin, list := range addresses {
+			coin = strings.ToUpper(coin)
+			if c.allowlist[coin] == nil {
+				c.allowlist[coin] = make(map[string]struct{})
+			}
+			for _, address := range list {
+				c.allowlist[coin][address] = struct{}{}
+			}
+		}
+	}
+}
+
+// WithHTTPClient makes the client send requests with httpClient.
+func WithHTTPClient(httpClient http.Client) Option {
+	return func(c *client) {
+		c.httpClient = httpClient
+	}
+}
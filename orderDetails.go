@@ -9,27 +9,30 @@ type OrderDetails struct {
 	UserOid          string  `json:"userOid"`
 	DealAmount       float64 `json:"dealAmount"`
 	DealOrders       struct {
-		Total     int  `json:"total"`
-		FirstPage bool `json:"firstPage"`
-		LastPage  bool `json:"lastPage"`
-		Datas     []struct {
-			Amount    float64 `json:"amount"`
-			DealValue float64 `json:"dealValue"`
-			Fee       float64 `json:"fee"`
-			DealPrice float64 `json:"dealPrice"`
-			FeeRate   float64 `json:"feeRate"`
-		} `json:"datas"`
-		CurrPageNo int `json:"currPageNo"`
-		Limit      int `json:"limit"`
-		PageNos    int `json:"pageNos"`
+		Total      int         `json:"total"`
+		FirstPage  bool        `json:"firstPage"`
+		LastPage   bool        `json:"lastPage"`
+		Datas      []OrderDeal `json:"datas"`
+		CurrPageNo int         `json:"currPageNo"`
+		Limit      int         `json:"limit"`
+		PageNos    int         `json:"pageNos"`
 	} `json:"dealOrders"`
 	CoinTypePair  string  `json:"coinTypePair"`
 	OrderPrice    float64 `json:"orderPrice"`
-	Type          string  `json:"type"`
+	Type          Side    `json:"type"`
 	OrderOid      string  `json:"orderOid"`
 	PendingAmount float64 `json:"pendingAmount"`
 }
 
+// OrderDeal struct represents kucoin data model.
+type OrderDeal struct {
+	Amount    float64 `json:"amount"`
+	DealValue float64 `json:"dealValue"`
+	Fee       float64 `json:"fee"`
+	DealPrice float64 `json:"dealPrice"`
+	FeeRate   float64 `json:"feeRate"`
+}
+
 type rawOrderDetails struct {
 	Success   bool         `json:"success"`
 	Code      string       `json:"code"`
@@ -0,0 +1,192 @@
+package kucoin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TimeInForce represents how long an order stays active at Kucoin.
+// Kucoin v1 keeps every order until it is filled or cancelled, so all
+// policies except GTC are emulated on the client side.
+type TimeInForce string
+
+// Supported time-in-force policies.
+const (
+	// GTC keeps the order until it is filled or cancelled (Kucoin default).
+	GTC TimeInForce = "GTC"
+	// IOC cancels whatever is left of the order right after placing it.
+	IOC TimeInForce = "IOC"
+	// FOK places the order only if the book can fill it entirely,
+	// then cancels whatever is left of it.
+	FOK TimeInForce = "FOK"
+	// GTT cancels the order once OrderOptions.ExpireAfter has elapsed.
+	GTT TimeInForce = "GTT"
+)
+
+func (tif TimeInForce) valid() bool {
+	switch tif {
+	case "", GTC, IOC, FOK, GTT:
+		return true
+	}
+	return false
+}
+
+// OrderOptions holds the optional execution semantics of an order.
+type OrderOptions struct {
+	// TimeInForce defaults to GTC when empty.
+	TimeInForce TimeInForce
+	// PostOnly rejects the order if it would cross the best price of the book.
+	PostOnly bool
+	// ExpireAfter is the lifetime of a GTT order. The expiry only lives in
+	// this process: if it exits before, the order stays on the book. Save
+	// PendingExpiries to restore them with ExpireOrderAt, or cancel the
+	// orders with FlushExpiries, before exiting.
+	ExpireAfter time.Duration
+}
+
+// PartialFillError is returned for a FOK order which the book couldn't fill
+// entirely once placed: Filled traded, Cancelled was cancelled.
+type PartialFillError struct {
+	OrderOid  string
+	Filled    float64
+	Cancelled float64
+}
+
+func (e *PartialFillError) Error() string {
+	return fmt.Sprintf("Fill-or-kill order %s partially filled: %v filled, %v cancelled", e.OrderOid, e.Filled, e.Cancelled)
+}
+
+// PendingExpiry is a GTT order waiting for its expiry.
+type PendingExpiry struct {
+	OrderOid string
+	Symbol   string
+	Side     Side
+	At       time.Time
+}
+
+// PendingExpiries returns the GTT orders waiting for their expiry, soonest
+// first.
+func (k *Kucoin) PendingExpiries() []PendingExpiry {
+	return k.gtt.pending(false)
+}
+
+// ExpireOrderAt cancels the order at the given time, e.g. to restore the
+// PendingExpiries saved by a previous process. Past times cancel it now.
+func (k *Kucoin) ExpireOrderAt(symbol, orderOid string, side Side, at time.Time) (err error) {
+	if len(symbol) < 1 || len(side) < 1 || len(orderOid) < 1 {
+		return ErrAllParamsRequired
+	}
+	if side, err = ParseSide(string(side)); err != nil {
+		return err
+	}
+	k.gtt.schedule(k.WithContext(context.WithoutCancel(k.reqContext())), strings.ToUpper(symbol), orderOid, side, time.Until(at))
+	return nil
+}
+
+// StopExpiries stops the pending expiries without cancelling their orders
+// and returns them, e.g. to save them before exiting.
+func (k *Kucoin) StopExpiries() []PendingExpiry {
+	return k.gtt.pending(true)
+}
+
+// FlushExpiries cancels the orders of the pending expiries now, e.g.
+// before exiting.
+func (k *Kucoin) FlushExpiries() error {
+	var errs []error
+	for _, e := range k.gtt.pending(true) {
+		if err := k.CancelOrder(e.Symbol, e.OrderOid, e.Side); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", e.OrderOid, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// canceller cancels GTT orders in background once they expire.
+type canceller struct {
+	mu     sync.Mutex
+	timers map[string]*expiry
+}
+
+type expiry struct {
+	symbol string
+	side   Side
+	at     time.Time
+	timer  *time.Timer
+}
+
+func newCanceller() *canceller {
+	return &canceller{
+		timers: make(map[string]*expiry),
+	}
+}
+
+func (c *canceller) schedule(k *Kucoin, symbol, orderOid string, side Side, after time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.timers[orderOid]; ok {
+		e.timer.Stop()
+	}
+	e := &expiry{symbol: symbol, side: side, at: time.Now().Add(after)}
+	e.timer = time.AfterFunc(after, func() {
+		c.mu.Lock()
+		if c.timers[orderOid] != e {
+			// Stopped or rescheduled meanwhile.
+			c.mu.Unlock()
+			return
+		}
+		delete(c.timers, orderOid)
+		c.mu.Unlock()
+		if err := k.CancelOrder(symbol, orderOid, side); err != nil && k.client.debug {
+			log.Printf("GTT cancel of %s failed: %s\n", orderOid, err)
+		}
+	})
+	c.timers[orderOid] = e
+}
+
+// pending returns the pending expiries, soonest first, and forgets them if
+// stop is true.
+func (c *canceller) pending(stop bool) []PendingExpiry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pending := make([]PendingExpiry, 0, len(c.timers))
+	for orderOid, e := range c.timers {
+		pending = append(pending, PendingExpiry{OrderOid: orderOid, Symbol: e.symbol, Side: e.side, At: e.at})
+		if stop {
+			e.timer.Stop()
+			delete(c.timers, orderOid)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].At.Before(pending[j].At)
+	})
+	return pending
+}
+
+// stop forgets the expiry of the order, e.g. when it was cancelled manually.
+func (c *canceller) stop(orderOid string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.timers[orderOid]; ok {
+		e.timer.Stop()
+		delete(c.timers, orderOid)
+	}
+}
+
+// stopSymbol forgets the expiries of the orders of the symbol.
+// Empty side matches both sides.
+func (c *canceller) stopSymbol(symbol string, side Side) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for orderOid, e := range c.timers {
+		if e.symbol == symbol && (len(side) < 1 || e.side == side) {
+			e.timer.Stop()
+			delete(c.timers, orderOid)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package papertrade
+
+import (
+	"sort"
+
+	"github.com/fiore/kucoin-go"
+)
+
+// epsilon is the amount below which an order or a level is considered empty.
+const epsilon = 1e-12
+
+type level struct {
+	price  float64
+	amount float64
+}
+
+// book is the local copy of a symbol order book.
+// Bids are sorted by descending price, asks by ascending price.
+type book struct {
+	bids []level
+	asks []level
+}
+
+func newBook(ordersBook kucoin.OrdersBook) *book {
+	b := &book{}
+	for _, l := range ordersBook.BUY {
+		if len(l) > 1 {
+			b.update(kucoin.Buy, l[0], l[1])
+		}
+	}
+	for _, l := range ordersBook.SELL {
+		if len(l) > 1 {
+			b.update(kucoin.Sell, l[0], l[1])
+		}
+	}
+	return b
+}
+
+// levels returns the side of the book holding orders of the direction.
+func (b *book) levels(direction kucoin.Side) *[]level {
+	if direction == kucoin.Buy {
+		return &b.bids
+	}
+	return &b.asks
+}
+
+// update adds delta to the amount at price, removing emptied levels.
+func (b *book) update(direction kucoin.Side, price, delta float64) {
+	levels := b.levels(direction)
+	i := sort.Search(len(*levels), func(i int) bool {
+		if direction == kucoin.Buy {
+			return (*levels)[i].price <= price
+		}
+		return (*levels)[i].price >= price
+	})
+	if i < len(*levels) && (*levels)[i].price == price {
+		(*levels)[i].amount += delta
+		if (*levels)[i].amount <= epsilon {
+			*levels = append((*levels)[:i], (*levels)[i+1:]...)
+		}
+		return
+	}
+	if delta <= epsilon {
+		return
+	}
+	*levels = append(*levels, level{})
+	copy((*levels)[i+1:], (*levels)[i:])
+	(*levels)[i] = level{price, delta}
+}
+
+// ordersBook returns the book in Kucoin format: [price, amount, volume] levels.
+func (b *book) ordersBook(limit int, direction kucoin.Side) (ordersBook kucoin.OrdersBook) {
+	convert := func(levels []level) [][]float64 {
+		if limit > 0 && len(levels) > limit {
+			levels = levels[:limit]
+		}
+		res := make([][]float64, 0, len(levels))
+		for _, l := range levels {
+			res = append(res, []float64{l.price, l.amount, l.price * l.amount})
+		}
+		return res
+	}
+	if direction != kucoin.Sell {
+		ordersBook.BUY = convert(b.bids)
+	}
+	if direction != kucoin.Buy {
+		ordersBook.SELL = convert(b.asks)
+	}
+	return
+}
@@ -0,0 +1,697 @@
+// Package papertrade implements kucoin.Trader with an in-process exchange
+// simulator, so strategies can switch between paper and live trading by
+// swapping the implementation.
+//
+// Orders are matched against live order books from Kucoin or against
+// recorded books and trades fed with ApplyBook, ApplyDelta and ApplyTrade.
+// Crossing orders are filled as taker at the book prices, resting orders
+// are filled as maker at their own price by trades going through them.
+// Fees are charged with Symbol.FeeRate in the received coin.
+package papertrade
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fiore/kucoin-go"
+	"github.com/fiore/kucoin-go/websocket"
+)
+
+// Custom errors returned by the simulator.
+var (
+	ErrInsufficientBalance = errors.New("Insufficient balance")
+	ErrOrderNotFound       = errors.New("Order not found")
+)
+
+// MarketData is the source of live market data. *kucoin.Kucoin implements it.
+type MarketData interface {
+	GetSymbol(symbol string) (kucoin.Symbol, error)
+	OrdersBook(symbol string, group, limit int, direction kucoin.Side) (kucoin.OrdersBook, error)
+}
+
+// Config holds the simulator settings.
+type Config struct {
+	// Market is the source of live books and symbols.
+	// Leave it nil when feeding recorded data.
+	Market MarketData
+	// Balances holds the initial available balance per coin.
+	Balances map[string]float64
+	// FeeRate is used for symbols without a known Symbol.FeeRate.
+	FeeRate float64
+	// Latency delays the moment an order starts matching.
+	Latency time.Duration
+	// Clock returns the current time. Defaults to time.Now.
+	Clock func() time.Time
+}
+
+type order struct {
+	oid        string
+	symbol     string
+	coin       string
+	pair       string
+	side       kucoin.Side
+	price      float64
+	amount     float64
+	dealAmount float64
+	dealValue  float64
+	fee        float64
+	deals      []kucoin.OrderDeal
+	createdAt  time.Time
+	updatedAt  time.Time
+	activeAt   time.Time
+	open       bool
+}
+
+func (o *order) pending() float64 {
+	return o.amount - o.dealAmount
+}
+
+// Exchange is an in-process exchange simulator implementing kucoin.Trader.
+type Exchange struct {
+	cfg Config
+
+	mu       sync.Mutex
+	seq      int
+	symbols  map[string]kucoin.Symbol
+	books    map[string]*book
+	balances map[string]*kucoin.CoinBalance
+	orders   map[string]*order
+	open     []*order
+	deals    []kucoin.MergedDeal
+}
+
+var _ kucoin.Trader = (*Exchange)(nil)
+
+// New returns a simulated exchange.
+func New(cfg Config) *Exchange {
+	if cfg.Clock == nil {
+		cfg.Clock = time.Now
+	}
+	e := &Exchange{
+		cfg:      cfg,
+		symbols:  make(map[string]kucoin.Symbol),
+		books:    make(map[string]*book),
+		balances: make(map[string]*kucoin.CoinBalance),
+		orders:   make(map[string]*order),
+	}
+	for coin, amount := range cfg.Balances {
+		e.balance(strings.ToUpper(coin)).Balance = amount
+	}
+	return e
+}
+
+func splitSymbol(symbol string) (coin, pair string, err error) {
+	coins := strings.Split(symbol, "-")
+	if len(coins) != 2 || len(coins[0]) < 1 || len(coins[1]) < 1 {
+		return "", "", kucoin.ErrNonExistingSymbol
+	}
+	return coins[0], coins[1], nil
+}
+
+func (e *Exchange) nextOid() string {
+	e.seq++
+	return fmt.Sprintf("paper%019d", e.seq)
+}
+
+func (e *Exchange) balance(coin string) *kucoin.CoinBalance {
+	b, ok := e.balances[coin]
+	if !ok {
+		b = &kucoin.CoinBalance{CoinType: coin}
+		e.balances[coin] = b
+	}
+	return b
+}
+
+func (e *Exchange) feeRate(symbol string) float64 {
+	if s, ok := e.symbols[symbol]; ok && s.FeeRate > 0 {
+		return s.FeeRate
+	}
+	return e.cfg.FeeRate
+}
+
+func (e *Exchange) knowSymbol(symbol string) {
+	if _, ok := e.symbols[symbol]; ok {
+		return
+	}
+	coin, pair, _ := splitSymbol(symbol)
+	e.symbols[symbol] = kucoin.Symbol{
+		Symbol:       symbol,
+		CoinType:     coin,
+		CoinTypePair: pair,
+		Trading:      true,
+	}
+}
+
+// SetSymbol sets the symbol meta data, FeeRate in particular.
+func (e *Exchange) SetSymbol(s kucoin.Symbol) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.symbols[strings.ToUpper(s.Symbol)] = s
+}
+
+// ApplyBook replaces the order book of the symbol and matches crossing orders.
+func (e *Exchange) ApplyBook(symbol string, ordersBook kucoin.OrdersBook) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	symbol = strings.ToUpper(symbol)
+	e.knowSymbol(symbol)
+	e.books[symbol] = newBook(ordersBook)
+	e.step()
+}
+
+// ApplyDelta applies an order book update received from the websocket
+// TOrderBook topic and matches crossing orders.
+// ADD actions add Count to the level at Price, CANCEL actions remove it.
+func (e *Exchange) ApplyDelta(ob websocket.OrderBook) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	symbol := strings.ToUpper(ob.Symbol)
+	e.knowSymbol(symbol)
+	b, ok := e.books[symbol]
+	if !ok {
+		b = &book{}
+		e.books[symbol] = b
+	}
+	delta := ob.Count
+	if strings.ToUpper(ob.Action) == "CANCEL" {
+		delta = -delta
+	}
+	b.update(ob.Type, ob.Price, delta)
+	e.step()
+}
+
+// ApplyTrade applies a trade received from the websocket THistory topic.
+// Resting orders the trade went through are filled as maker at their price.
+func (e *Exchange) ApplyTrade(h websocket.History) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	symbol := strings.ToUpper(h.Symbol)
+	e.knowSymbol(symbol)
+	s := e.symbols[symbol]
+	s.LastDealPrice = h.Price
+	e.symbols[symbol] = s
+	e.step()
+
+	// A SELL trade hits the bids, a BUY trade lifts the asks.
+	side := h.Direction.Opposite()
+	now := e.cfg.Clock()
+	var candidates []*order
+	for _, o := range e.open {
+		if o.symbol != symbol || o.side != side || o.activeAt.After(now) {
+			continue
+		}
+		if side == kucoin.Buy && o.price >= h.Price || side == kucoin.Sell && o.price <= h.Price {
+			candidates = append(candidates, o)
+		}
+	}
+	// Price priority, then time priority.
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if side == kucoin.Buy {
+			return candidates[i].price > candidates[j].price
+		}
+		return candidates[i].price < candidates[j].price
+	})
+	remaining := h.Count
+	for _, o := range candidates {
+		if remaining <= epsilon {
+			break
+		}
+		amount := o.pending()
+		if amount > remaining {
+			amount = remaining
+		}
+		e.fill(o, o.price, amount, false)
+		remaining -= amount
+	}
+	e.prune()
+}
+
+// Refresh fetches the book and the symbol meta data from the live market.
+func (e *Exchange) Refresh(symbol string) error {
+	if e.cfg.Market == nil {
+		return nil
+	}
+	symbol = strings.ToUpper(symbol)
+	s, err := e.cfg.Market.GetSymbol(symbol)
+	if err != nil {
+		return err
+	}
+	ordersBook, err := e.cfg.Market.OrdersBook(symbol, 0, 0, "")
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	e.symbols[symbol] = s
+	e.mu.Unlock()
+	e.ApplyBook(symbol, ordersBook)
+	return nil
+}
+
+// step matches every active order against the book.
+func (e *Exchange) step() {
+	now := e.cfg.Clock()
+	for _, o := range e.open {
+		if o.open && !o.activeAt.After(now) {
+			e.match(o)
+		}
+	}
+	e.prune()
+}
+
+// prune drops closed orders from the open list.
+func (e *Exchange) prune() {
+	open := e.open[:0]
+	for _, o := range e.open {
+		if o.open {
+			open = append(open, o)
+		}
+	}
+	e.open = open
+}
+
+// match fills the order as taker against the crossing levels of the book.
+func (e *Exchange) match(o *order) {
+	b, ok := e.books[o.symbol]
+	if !ok {
+		return
+	}
+	levels := b.levels(o.side.Opposite())
+	for len(*levels) > 0 && o.open {
+		l := &(*levels)[0]
+		if o.side == kucoin.Buy && o.price < l.price || o.side == kucoin.Sell && o.price > l.price {
+			return
+		}
+		amount := o.pending()
+		if amount > l.amount {
+			amount = l.amount
+		}
+		e.fill(o, l.price, amount, true)
+		l.amount -= amount
+		if l.amount <= epsilon {
+			*levels = (*levels)[1:]
+		}
+	}
+}
+
+// fill executes amount of the order at price and settles balances.
+func (e *Exchange) fill(o *order, price, amount float64, taker bool) {
+	feeRate := e.feeRate(o.symbol)
+	value := price * amount
+	coin, pair := e.balance(o.coin), e.balance(o.pair)
+	var fee float64
+	if o.side == kucoin.Buy {
+		fee = amount * feeRate
+		frozen := o.price * amount
+		pair.FreezeBalance -= frozen
+		pair.Balance += frozen - value
+		coin.Balance += amount - fee
+	} else {
+		fee = value * feeRate
+		coin.FreezeBalance -= amount
+		pair.Balance += value - fee
+	}
+
+	now := e.cfg.Clock()
+	o.dealAmount += amount
+	o.dealValue += value
+	o.fee += fee
+	o.updatedAt = now
+	o.deals = append(o.deals, kucoin.OrderDeal{
+		Amount:    amount,
+		DealValue: value,
+		Fee:       fee,
+		DealPrice: price,
+		FeeRate:   feeRate,
+	})
+	dealDirection := o.side
+	if !taker {
+		dealDirection = o.side.Opposite()
+	}
+	e.deals = append(e.deals, kucoin.MergedDeal{
+		CreatedAt:     kucoin.NewMillis(now),
+		Amount:        amount,
+		DealValue:     value,
+		DealPrice:     price,
+		Fee:           fee,
+		FeeRate:       feeRate,
+		Oid:           e.nextOid(),
+		OrderOid:      o.oid,
+		CoinType:      o.coin,
+		CoinTypePair:  o.pair,
+		Direction:     o.side,
+		DealDirection: dealDirection,
+	})
+	if o.pending() <= epsilon {
+		e.close(o)
+	}
+}
+
+// close closes the order and releases what is left of its frozen balance.
+func (e *Exchange) close(o *order) {
+	o.open = false
+	pending := o.pending()
+	if pending <= epsilon {
+		return
+	}
+	if o.side == kucoin.Buy {
+		pair := e.balance(o.pair)
+		pair.FreezeBalance -= o.price * pending
+		pair.Balance += o.price * pending
+	} else {
+		coin := e.balance(o.coin)
+		coin.FreezeBalance -= pending
+		coin.Balance += pending
+	}
+}
+
+// Deals returns all simulated deals in execution order.
+func (e *Exchange) Deals() []kucoin.MergedDeal {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]kucoin.MergedDeal(nil), e.deals...)
+}
+
+// GetSymbol returns the symbol meta data, fetched from the live market if any.
+func (e *Exchange) GetSymbol(symbol string) (s kucoin.Symbol, err error) {
+	symbol = strings.ToUpper(symbol)
+	if e.cfg.Market != nil {
+		if s, err = e.cfg.Market.GetSymbol(symbol); err != nil {
+			return
+		}
+		e.mu.Lock()
+		e.symbols[symbol] = s
+		e.mu.Unlock()
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	s, ok := e.symbols[symbol]
+	if !ok {
+		return s, kucoin.ErrNonExistingSymbol
+	}
+	return
+}
+
+// OrdersBook returns the simulated order book, refreshed from the live market if any.
+func (e *Exchange) OrdersBook(symbol string, group, limit int, direction kucoin.Side) (ordersBook kucoin.OrdersBook, err error) {
+	if len(symbol) < 1 {
+		return ordersBook, kucoin.ErrSymbolRequired
+	}
+	if err = e.Refresh(symbol); err != nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	b, ok := e.books[strings.ToUpper(symbol)]
+	if !ok {
+		return ordersBook, kucoin.ErrNonExistingSymbol
+	}
+	return b.ordersBook(limit, kucoin.Side(strings.ToUpper(string(direction)))), nil
+}
+
+// GetCoinBalance returns the simulated balance of the coin.
+func (e *Exchange) GetCoinBalance(coin string) (coinBalance kucoin.CoinBalance, err error) {
+	if len(coin) < 1 {
+		return coinBalance, kucoin.ErrSymbolRequired
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return *e.balance(strings.ToUpper(coin)), nil
+}
+
+// Balances returns the simulated balances of all coins.
+func (e *Exchange) Balances() []kucoin.CoinBalance {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	balances := make([]kucoin.CoinBalance, 0, len(e.balances))
+	for _, b := range e.balances {
+		balances = append(balances, *b)
+	}
+	sort.Slice(balances, func(i, j int) bool {
+		return balances[i].CoinType < balances[j].CoinType
+	})
+	return balances
+}
+
+// CreateOrder places a simulated order, freezing the needed balance.
+func (e *Exchange) CreateOrder(symbol string, side kucoin.Side, price, amount float64) (orderOid string, err error) {
+	if len(symbol) < 1 || len(side) < 1 || price <= 0.0 || amount <= 0.0 {
+		return orderOid, kucoin.ErrAllParamsRequired
+	}
+	symbol = strings.ToUpper(symbol)
+	coin, pair, err := splitSymbol(symbol)
+	if err != nil {
+		return
+	}
+	if side, err = kucoin.ParseSide(string(side)); err != nil {
+		return
+	}
+	if err = e.Refresh(symbol); err != nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.knowSymbol(symbol)
+	frozen, required := e.balance(coin), amount
+	if side == kucoin.Buy {
+		frozen, required = e.balance(pair), price*amount
+	}
+	if frozen.Balance < required {
+		return orderOid, ErrInsufficientBalance
+	}
+	frozen.Balance -= required
+	frozen.FreezeBalance += required
+
+	now := e.cfg.Clock()
+	o := &order{
+		oid:       e.nextOid(),
+		symbol:    symbol,
+		coin:      coin,
+		pair:      pair,
+		side:      side,
+		price:     price,
+		amount:    amount,
+		createdAt: now,
+		updatedAt: now,
+		activeAt:  now.Add(e.cfg.Latency),
+		open:      true,
+	}
+	e.orders[o.oid] = o
+	e.open = append(e.open, o)
+	if e.cfg.Latency <= 0 {
+		e.match(o)
+		e.prune()
+	}
+	return o.oid, nil
+}
+
+// CreateOrderByString places a simulated order with prices and amounts as strings.
+func (e *Exchange) CreateOrderByString(symbol string, side kucoin.Side, price, amount string) (orderOid string, err error) {
+	if len(symbol) < 1 || len(side) < 1 || len(price) < 1 || len(amount) < 1 {
+		return orderOid, kucoin.ErrAllParamsRequired
+	}
+	p, err := strconv.ParseFloat(price, 64)
+	if err != nil {
+		return
+	}
+	a, err := strconv.ParseFloat(amount, 64)
+	if err != nil {
+		return
+	}
+	return e.CreateOrder(symbol, side, p, a)
+}
+
+// CancelOrder cancels a simulated order, releasing its frozen balance.
+func (e *Exchange) CancelOrder(symbol, orderOid string, side kucoin.Side) (err error) {
+	if len(symbol) < 1 || len(side) < 1 || len(orderOid) < 1 {
+		return kucoin.ErrAllParamsRequired
+	}
+	if side, err = kucoin.ParseSide(string(side)); err != nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	o, ok := e.orders[orderOid]
+	if !ok || !o.open || o.symbol != strings.ToUpper(symbol) || o.side != side {
+		return ErrOrderNotFound
+	}
+	o.updatedAt = e.cfg.Clock()
+	e.close(o)
+	e.prune()
+	return nil
+}
+
+// CancelAllOrders cancels all simulated orders of the symbol and optional side.
+func (e *Exchange) CancelAllOrders(symbol string, side kucoin.Side) (err error) {
+	if len(symbol) < 1 {
+		return kucoin.ErrSymbolRequired
+	}
+	if len(side) > 0 {
+		if side, err = kucoin.ParseSide(string(side)); err != nil {
+			return
+		}
+	}
+	symbol = strings.ToUpper(symbol)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	now := e.cfg.Clock()
+	for _, o := range e.open {
+		if o.symbol == symbol && (len(side) < 1 || o.side == side) {
+			o.updatedAt = now
+			e.close(o)
+		}
+	}
+	e.prune()
+	return nil
+}
+
+// ListActiveMapOrders returns the open simulated orders of the symbol.
+func (e *Exchange) ListActiveMapOrders(symbol string, side kucoin.Side) (activeMapOrders kucoin.ActiveMapOrder, err error) {
+	if len(symbol) < 1 {
+		return activeMapOrders, kucoin.ErrSymbolRequired
+	}
+	if len(side) > 0 {
+		if side, err = kucoin.ParseSide(string(side)); err != nil {
+			return
+		}
+	}
+	symbol = strings.ToUpper(symbol)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, o := range e.open {
+		if o.symbol != symbol || len(side) > 0 && o.side != side {
+			continue
+		}
+		mo := kucoin.MapOrder{
+			Oid:           o.oid,
+			Type:          o.side,
+			CoinType:      o.coin,
+			CoinTypePair:  o.pair,
+			Direction:     o.side,
+			Price:         o.price,
+			DealAmount:    o.dealAmount,
+			PendingAmount: o.pending(),
+			CreatedAt:     kucoin.NewMillis(o.createdAt),
+			UpdatedAt:     kucoin.NewMillis(o.updatedAt),
+		}
+		if o.side == kucoin.Buy {
+			activeMapOrders.BUY = append(activeMapOrders.BUY, mo)
+		} else {
+			activeMapOrders.SELL = append(activeMapOrders.SELL, mo)
+		}
+	}
+	return
+}
+
+// OrderDetails returns the simulated order along with a page of its deals.
+func (e *Exchange) OrderDetails(symbol string, side kucoin.Side, orderOid string, limit, page int) (orderDetails kucoin.OrderDetails, err error) {
+	if len(symbol) < 1 || len(side) < 1 || len(orderOid) < 1 {
+		return orderDetails, kucoin.ErrAllParamsRequired
+	}
+	if side, err = kucoin.ParseSide(string(side)); err != nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	o, ok := e.orders[orderOid]
+	if !ok || o.symbol != strings.ToUpper(symbol) || o.side != side {
+		return orderDetails, ErrOrderNotFound
+	}
+	if limit <= 0 || limit > 20 {
+		limit = 20
+	}
+	if page <= 0 {
+		page = 1
+	}
+
+	orderDetails = kucoin.OrderDetails{
+		CoinType:       o.coin,
+		CoinTypePair:   o.pair,
+		DealValueTotal: o.dealValue,
+		FeeTotal:       o.fee,
+		DealAmount:     o.dealAmount,
+		OrderPrice:     o.price,
+		Type:           o.side,
+		OrderOid:       o.oid,
+	}
+	if o.dealAmount > 0 {
+		orderDetails.DealPriceAverage = o.dealValue / o.dealAmount
+	}
+	if o.open {
+		orderDetails.PendingAmount = o.pending()
+	}
+	total := len(o.deals)
+	from, to := (page-1)*limit, page*limit
+	if from > total {
+		from = total
+	}
+	if to > total {
+		to = total
+	}
+	orderDetails.DealOrders.Total = total
+	orderDetails.DealOrders.Limit = limit
+	orderDetails.DealOrders.CurrPageNo = page
+	orderDetails.DealOrders.PageNos = (total + limit - 1) / limit
+	orderDetails.DealOrders.FirstPage = page == 1
+	orderDetails.DealOrders.LastPage = to == total
+	orderDetails.DealOrders.Datas = append([]kucoin.OrderDeal(nil), o.deals[from:to]...)
+	return
+}
+
+// ListMergedDealtOrders returns the simulated deals, newest first.
+func (e *Exchange) ListMergedDealtOrders(symbol string, side kucoin.Side, limit, page int, since, before time.Time) (mergedDealtOrders kucoin.MergedDealtOrder, err error) {
+	symbol = strings.ToUpper(symbol)
+	var coin, pair string
+	if len(symbol) > 0 {
+		if coin, pair, err = splitSymbol(symbol); err != nil {
+			return
+		}
+	}
+	if len(side) > 0 {
+		if side, err = kucoin.ParseSide(string(side)); err != nil {
+			return
+		}
+	}
+	maxLimit := 20
+	if len(symbol) > 0 {
+		maxLimit = 100
+	}
+	if limit <= 0 || limit > maxLimit {
+		limit = maxLimit
+	}
+	if page <= 0 {
+		page = 1
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var deals []kucoin.MergedDeal
+	for i := len(e.deals) - 1; i >= 0; i-- {
+		d := e.deals[i]
+		if len(symbol) > 0 && (d.CoinType != coin || d.CoinTypePair != pair) ||
+			len(side) > 0 && d.Direction != side ||
+			!since.IsZero() && d.CreatedAt.Before(since) ||
+			!before.IsZero() && !d.CreatedAt.Before(before) {
+			continue
+		}
+		deals = append(deals, d)
+	}
+	from, to := (page-1)*limit, page*limit
+	if from > len(deals) {
+		from = len(deals)
+	}
+	if to > len(deals) {
+		to = len(deals)
+	}
+	mergedDealtOrders.Total = len(deals)
+	mergedDealtOrders.Limit = limit
+	mergedDealtOrders.Page = page
+	mergedDealtOrders.Datas = deals[from:to]
+	return
+}
@@ -0,0 +1,87 @@
+package papertrade_test
+
+import (
+	"testing"
+	"time"
+
+	kucoinGo "github.com/fiore/kucoin-go"
+	"github.com/fiore/kucoin-go/papertrade"
+	"github.com/fiore/kucoin-go/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+func newExchange() *papertrade.Exchange {
+	e := papertrade.New(papertrade.Config{
+		Balances: map[string]float64{"BTC": 1, "KCS": 100},
+	})
+	e.SetSymbol(kucoinGo.Symbol{Symbol: "KCS-BTC", CoinType: "KCS", CoinTypePair: "BTC", FeeRate: 0.001})
+	e.ApplyBook("KCS-BTC", kucoinGo.OrdersBook{
+		BUY:  [][]float64{{0.0009, 10, 0.009}, {0.0008, 20, 0.016}},
+		SELL: [][]float64{{0.0011, 10, 0.011}, {0.0012, 20, 0.024}},
+	})
+	return e
+}
+
+func TestTakerOrder(t *testing.T) {
+	e := newExchange()
+
+	orderOid, err := e.CreateOrder("KCS-BTC", kucoinGo.Buy, 0.0012, 15)
+	require.NoError(t, err)
+
+	orderDetails, err := e.OrderDetails("KCS-BTC", kucoinGo.Buy, orderOid, 0, 0)
+	require.NoError(t, err)
+	require.InDelta(t, 15, orderDetails.DealAmount, 1e-9)
+	require.InDelta(t, 0.011+0.006, orderDetails.DealValueTotal, 1e-9)
+	require.InDelta(t, 0.015, orderDetails.FeeTotal, 1e-9)
+	require.Len(t, orderDetails.DealOrders.Datas, 2)
+	require.Zero(t, orderDetails.PendingAmount)
+
+	kcs, _ := e.GetCoinBalance("KCS")
+	require.InDelta(t, 100+15-0.015, kcs.Balance, 1e-9)
+	btc, _ := e.GetCoinBalance("BTC")
+	require.InDelta(t, 1-0.017, btc.Balance, 1e-9)
+	require.InDelta(t, 0, btc.FreezeBalance, 1e-9)
+
+	ordersBook, err := e.OrdersBook("KCS-BTC", 0, 0, kucoinGo.Sell)
+	require.NoError(t, err)
+	require.Equal(t, [][]float64{{0.0012, 15, 0.0012 * 15}}, ordersBook.SELL)
+
+	mergedDealtOrders, err := e.ListMergedDealtOrders("KCS-BTC", "", 0, 0, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Equal(t, 2, mergedDealtOrders.Total)
+	require.Equal(t, kucoinGo.Buy, mergedDealtOrders.Datas[0].DealDirection)
+}
+
+func TestMakerOrder(t *testing.T) {
+	e := newExchange()
+
+	orderOid, err := e.CreateOrder("KCS-BTC", kucoinGo.Sell, 0.001, 30)
+	require.NoError(t, err)
+	kcs, _ := e.GetCoinBalance("KCS")
+	require.InDelta(t, 70, kcs.Balance, 1e-9)
+	require.InDelta(t, 30, kcs.FreezeBalance, 1e-9)
+
+	e.ApplyTrade(websocket.History{Symbol: "KCS-BTC", Price: 0.0011, Count: 12, Direction: kucoinGo.Buy})
+	active, err := e.ListActiveMapOrders("KCS-BTC", "")
+	require.NoError(t, err)
+	require.Len(t, active.SELL, 1)
+	require.InDelta(t, 12, active.SELL[0].DealAmount, 1e-9)
+
+	btc, _ := e.GetCoinBalance("BTC")
+	require.InDelta(t, 1+0.012*(1-0.001), btc.Balance, 1e-9)
+
+	require.NoError(t, e.CancelOrder("KCS-BTC", orderOid, kucoinGo.Sell))
+	kcs, _ = e.GetCoinBalance("KCS")
+	require.InDelta(t, 88, kcs.Balance, 1e-9)
+	require.InDelta(t, 0, kcs.FreezeBalance, 1e-9)
+	require.Equal(t, papertrade.ErrOrderNotFound, e.CancelOrder("KCS-BTC", orderOid, kucoinGo.Sell))
+}
+
+func TestInsufficientBalance(t *testing.T) {
+	e := newExchange()
+
+	_, err := e.CreateOrder("KCS-BTC", kucoinGo.Buy, 0.001, 2000)
+	require.Equal(t, papertrade.ErrInsufficientBalance, err)
+	_, err = e.CreateOrder("KCS-BTC", "TEST", 0.001, 1)
+	require.Error(t, err)
+}
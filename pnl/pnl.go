@@ -0,0 +1,262 @@
+// Package pnl computes positions, cost basis and realized and unrealized
+// profit and loss from the fills of Kucoin spot trading.
+//
+// Amounts are in the traded coin, prices, costs and PnL in the pair coin,
+// e.g. in BTC for KCS-BTC. Book marshals to JSON to be persisted and
+// resumed with later fills.
+package pnl
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fiore/kucoin-go"
+)
+
+// epsilon is the amount below which a lot or a position is considered empty.
+const epsilon = 1e-12
+
+// ErrUnknownMethod is returned for cost basis methods other than FIFO, LIFO and AverageCost.
+var ErrUnknownMethod = errors.New("Unknown cost basis method")
+
+// Method represents a cost basis method.
+type Method string
+
+// Cost basis methods.
+const (
+	FIFO        Method = "FIFO"
+	LIFO        Method = "LIFO"
+	AverageCost Method = "AVERAGE"
+)
+
+// Fill is a normalized deal of an order.
+type Fill struct {
+	Id      string      `json:"id"`
+	OrderId string      `json:"orderId"`
+	Symbol  string      `json:"symbol"`
+	Side    kucoin.Side `json:"side"`
+	Price   float64     `json:"price"`
+	Amount  float64     `json:"amount"`
+	// Fee is charged in the received coin: the traded coin when buying,
+	// the pair coin when selling.
+	Fee  float64   `json:"fee"`
+	Time time.Time `json:"time"`
+}
+
+// FromMergedDeals returns the fills of ListMergedDealtOrders, oldest first.
+func FromMergedDeals(deals []kucoin.MergedDeal) []Fill {
+	fills := make([]Fill, 0, len(deals))
+	for _, d := range deals {
+		fills = append(fills, Fill{
+			Id:      d.Oid,
+			OrderId: d.OrderOid,
+			Symbol:  strings.ToUpper(d.CoinType + "-" + d.CoinTypePair),
+			Side:    d.Direction,
+			Price:   d.DealPrice,
+			Amount:  d.Amount,
+			Fee:     d.Fee,
+			Time:    d.CreatedAt.Time,
+		})
+	}
+	sort.SliceStable(fills, func(i, j int) bool {
+		return fills[i].Time.Before(fills[j].Time)
+	})
+	return fills
+}
+
+// FromOrderDetails returns the fills of the deals of OrderDetails.
+// Kucoin doesn't time them, so they are all timed at.
+func FromOrderDetails(details kucoin.OrderDetails, at time.Time) []Fill {
+	fills := make([]Fill, 0, len(details.DealOrders.Datas))
+	for _, d := range details.DealOrders.Datas {
+		fills = append(fills, Fill{
+			OrderId: details.OrderOid,
+			Symbol:  strings.ToUpper(details.CoinType + "-" + details.CoinTypePair),
+			Side:    details.Type,
+			Price:   d.DealPrice,
+			Amount:  d.Amount,
+			Fee:     d.Fee,
+			Time:    at,
+		})
+	}
+	return fills
+}
+
+// Lot is an open amount bought at Price, fees included.
+type Lot struct {
+	Time   time.Time `json:"time"`
+	Amount float64   `json:"amount"`
+	Price  float64   `json:"price"`
+}
+
+// Position is the open amount and the PnL of a symbol.
+type Position struct {
+	Symbol string `json:"symbol"`
+	// Amount is the open amount, Cost its cost basis.
+	Amount float64 `json:"amount"`
+	Cost   float64 `json:"cost"`
+	Lots   []Lot   `json:"lots"`
+	// Realized is the PnL of the sold amounts, net of fees.
+	Realized float64 `json:"realized"`
+	// Fees is the value of all paid fees.
+	Fees float64 `json:"fees"`
+	// Unmatched is the amount sold without a known cost basis, e.g. deposited
+	// coins. It is realized at zero cost.
+	Unmatched float64 `json:"unmatched"`
+	// Price is the last marked price, Unrealized the PnL of the open amount at it.
+	Price      float64   `json:"price"`
+	Unrealized float64   `json:"unrealized"`
+	Updated    time.Time `json:"updated"`
+}
+
+// AverageCost returns the average cost of the open amount.
+func (p *Position) AverageCost() float64 {
+	if p.Amount <= epsilon {
+		return 0
+	}
+	return p.Cost / p.Amount
+}
+
+// Book holds the positions of all symbols under a cost basis method.
+type Book struct {
+	Method    Method               `json:"method"`
+	Positions map[string]*Position `json:"positions"`
+}
+
+// New returns an empty book computing cost basis with method.
+func New(method Method) (*Book, error) {
+	switch method {
+	case FIFO, LIFO, AverageCost:
+	default:
+		return nil, ErrUnknownMethod
+	}
+	return &Book{Method: method, Positions: make(map[string]*Position)}, nil
+}
+
+// Position returns the position of the symbol, creating it if needed.
+func (b *Book) Position(symbol string) *Position {
+	symbol = strings.ToUpper(symbol)
+	if b.Positions == nil {
+		b.Positions = make(map[string]*Position)
+	}
+	p, ok := b.Positions[symbol]
+	if !ok {
+		p = &Position{Symbol: symbol}
+		b.Positions[symbol] = p
+	}
+	return p
+}
+
+// Add applies fills in order. Fills must be added oldest first.
+func (b *Book) Add(fills ...Fill) error {
+	for _, f := range fills {
+		side, err := kucoin.ParseSide(string(f.Side))
+		if err != nil {
+			return err
+		}
+		p := b.Position(f.Symbol)
+		if side == kucoin.Buy {
+			b.buy(p, f)
+		} else {
+			b.sell(p, f)
+		}
+		if f.Time.After(p.Updated) {
+			p.Updated = f.Time
+		}
+		p.mark()
+	}
+	return nil
+}
+
+func (b *Book) buy(p *Position, f Fill) {
+	received := f.Amount - f.Fee
+	cost := f.Price * f.Amount
+	p.Fees += f.Fee * f.Price
+	if received <= epsilon {
+		p.Realized -= cost
+		return
+	}
+	lot := Lot{Time: f.Time, Amount: received, Price: cost / received}
+	if b.Method == AverageCost && len(p.Lots) > 0 {
+		avg := &p.Lots[0]
+		avg.Price = (avg.Price*avg.Amount + cost) / (avg.Amount + received)
+		avg.Amount += received
+		avg.Time = f.Time
+	} else {
+		p.Lots = append(p.Lots, lot)
+	}
+	p.Amount += received
+	p.Cost += cost
+}
+
+func (b *Book) sell(p *Position, f Fill) {
+	proceeds := f.Price*f.Amount - f.Fee
+	p.Fees += f.Fee
+	remaining, cost := f.Amount, 0.0
+	for remaining > epsilon && len(p.Lots) > 0 {
+		i := 0
+		if b.Method == LIFO {
+			i = len(p.Lots) - 1
+		}
+		lot := &p.Lots[i]
+		amount := math.Min(remaining, lot.Amount)
+		cost += amount * lot.Price
+		lot.Amount -= amount
+		remaining -= amount
+		if lot.Amount <= epsilon {
+			p.Lots = append(p.Lots[:i], p.Lots[i+1:]...)
+		}
+	}
+	if remaining > epsilon {
+		p.Unmatched += remaining
+	}
+	p.Realized += proceeds - cost
+	p.Amount, p.Cost = 0, 0
+	for _, lot := range p.Lots {
+		p.Amount += lot.Amount
+		p.Cost += lot.Amount * lot.Price
+	}
+}
+
+func (p *Position) mark() {
+	if p.Price > 0 {
+		p.Unrealized = p.Amount*p.Price - p.Cost
+	}
+}
+
+// Mark values the open positions at the last deal prices of symbols.
+func (b *Book) Mark(symbols []kucoin.Symbol) {
+	for _, s := range symbols {
+		p, ok := b.Positions[strings.ToUpper(s.Symbol)]
+		if !ok || s.LastDealPrice <= 0 {
+			continue
+		}
+		p.Price = s.LastDealPrice
+		p.mark()
+	}
+}
+
+// Realized returns the realized PnL per pair coin.
+func (b *Book) Realized() map[string]float64 {
+	return b.sum(func(p *Position) float64 { return p.Realized })
+}
+
+// Unrealized returns the unrealized PnL per pair coin.
+func (b *Book) Unrealized() map[string]float64 {
+	return b.sum(func(p *Position) float64 { return p.Unrealized })
+}
+
+func (b *Book) sum(value func(p *Position) float64) map[string]float64 {
+	res := make(map[string]float64)
+	for symbol, p := range b.Positions {
+		pair := symbol
+		if i := strings.LastIndex(symbol, "-"); i >= 0 {
+			pair = symbol[i+1:]
+		}
+		res[pair] += value(p)
+	}
+	return res
+}
@@ -0,0 +1,83 @@
+package pnl_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	kucoinGo "github.com/fiore/kucoin-go"
+	"github.com/fiore/kucoin-go/pnl"
+	"github.com/stretchr/testify/require"
+)
+
+func fills() []pnl.Fill {
+	t := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	return []pnl.Fill{
+		{Symbol: "KCS-BTC", Side: kucoinGo.Buy, Price: 1, Amount: 10, Time: t},
+		{Symbol: "KCS-BTC", Side: kucoinGo.Buy, Price: 2, Amount: 10, Time: t.Add(time.Minute)},
+		{Symbol: "KCS-BTC", Side: kucoinGo.Sell, Price: 3, Amount: 10, Fee: 0.5, Time: t.Add(2 * time.Minute)},
+	}
+}
+
+func TestMethods(t *testing.T) {
+	for method, want := range map[pnl.Method]struct{ realized, cost float64 }{
+		pnl.FIFO:        {30 - 0.5 - 10, 20},
+		pnl.LIFO:        {30 - 0.5 - 20, 10},
+		pnl.AverageCost: {30 - 0.5 - 15, 15},
+	} {
+		book, err := pnl.New(method)
+		require.NoError(t, err)
+		require.NoError(t, book.Add(fills()...))
+		book.Mark([]kucoinGo.Symbol{{Symbol: "KCS-BTC", LastDealPrice: 2.5}})
+
+		p := book.Position("KCS-BTC")
+		require.InDelta(t, 10, p.Amount, 1e-9, method)
+		require.InDelta(t, want.cost, p.Cost, 1e-9, method)
+		require.InDelta(t, want.realized, p.Realized, 1e-9, method)
+		require.InDelta(t, 25-want.cost, p.Unrealized, 1e-9, method)
+		require.InDelta(t, want.realized, book.Realized()["BTC"], 1e-9, method)
+	}
+
+	_, err := pnl.New("TEST")
+	require.Equal(t, pnl.ErrUnknownMethod, err)
+}
+
+func TestFees(t *testing.T) {
+	book, err := pnl.New(pnl.FIFO)
+	require.NoError(t, err)
+	// Buy fees are charged in the bought coin, raising the cost per coin.
+	require.NoError(t, book.Add(pnl.Fill{Symbol: "KCS-BTC", Side: kucoinGo.Buy, Price: 1, Amount: 10, Fee: 2}))
+	p := book.Position("KCS-BTC")
+	require.InDelta(t, 8, p.Amount, 1e-9)
+	require.InDelta(t, 1.25, p.AverageCost(), 1e-9)
+	require.InDelta(t, 2, p.Fees, 1e-9)
+
+	// Coins sold beyond the position are realized at zero cost.
+	require.NoError(t, book.Add(pnl.Fill{Symbol: "KCS-BTC", Side: kucoinGo.Sell, Price: 2, Amount: 9}))
+	require.InDelta(t, 1, p.Unmatched, 1e-9)
+	require.InDelta(t, 18-10, p.Realized, 1e-9)
+	require.InDelta(t, 0, p.Amount, 1e-9)
+}
+
+func TestPersist(t *testing.T) {
+	book, err := pnl.New(pnl.FIFO)
+	require.NoError(t, err)
+	require.NoError(t, book.Add(fills()[:2]...))
+
+	b, err := json.Marshal(book)
+	require.NoError(t, err)
+	var resumed pnl.Book
+	require.NoError(t, json.Unmarshal(b, &resumed))
+	require.NoError(t, resumed.Add(fills()[2]))
+	require.InDelta(t, 30-0.5-10, resumed.Position("KCS-BTC").Realized, 1e-9)
+}
+
+func TestFromMergedDeals(t *testing.T) {
+	fills := pnl.FromMergedDeals([]kucoinGo.MergedDeal{
+		{CreatedAt: kucoinGo.MillisOf(2000), Oid: "d2", CoinType: "KCS", CoinTypePair: "BTC", Direction: kucoinGo.Sell},
+		{CreatedAt: kucoinGo.MillisOf(1000), Oid: "d1", CoinType: "KCS", CoinTypePair: "BTC", Direction: kucoinGo.Buy},
+	})
+	require.Len(t, fills, 2)
+	require.Equal(t, "d1", fills[0].Id)
+	require.Equal(t, "KCS-BTC", fills[0].Symbol)
+}
@@ -0,0 +1,109 @@
+package kucoin
+
+import (
+	"sort"
+	"strings"
+)
+
+// PortfolioItem is a non-zero coin balance valued in the portfolio quote coin.
+type PortfolioItem struct {
+	CoinBalance
+	// Price is the price of one coin in quote coin.
+	Price       float64
+	FreeValue   float64
+	FrozenValue float64
+	Value       float64
+	// Route holds the symbols used to price the coin, e.g. [NEO-BTC BTC-USDT].
+	Route []string
+}
+
+// Portfolio holds all non-zero balances valued in the Quote coin.
+type Portfolio struct {
+	Quote  string
+	Items  []PortfolioItem
+	Free   float64
+	Frozen float64
+	Total  float64
+	// Unvalued holds the coins without any market route to the quote coin.
+	Unvalued []CoinBalance
+}
+
+type conversion struct {
+	price float64
+	route []string
+}
+
+// NewPortfolio values balances in quote coin with the last deal prices of symbols.
+// Coins without a direct market to quote coin are routed through the pairs
+// with the fewest intermediate markets.
+func NewPortfolio(balances []CoinBalance, symbols []Symbol, quote string) (portfolio Portfolio) {
+	quote = strings.ToUpper(quote)
+	portfolio.Quote = quote
+
+	// Walk the markets breadth first from the quote coin.
+	conversions := map[string]conversion{quote: {price: 1}}
+	markets := make([]Symbol, 0, len(symbols))
+	for _, s := range symbols {
+		if s.LastDealPrice > 0 {
+			markets = append(markets, s)
+		}
+	}
+	sort.Slice(markets, func(i, j int) bool {
+		return markets[i].Symbol < markets[j].Symbol
+	})
+	frontier := []string{quote}
+	for len(frontier) > 0 {
+		var next []string
+		for _, coin := range frontier {
+			c := conversions[coin]
+			for _, s := range markets {
+				var other string
+				var price float64
+				switch {
+				case strings.ToUpper(s.CoinTypePair) == coin:
+					other, price = strings.ToUpper(s.CoinType), s.LastDealPrice*c.price
+				case strings.ToUpper(s.CoinType) == coin:
+					other, price = strings.ToUpper(s.CoinTypePair), c.price/s.LastDealPrice
+				default:
+					continue
+				}
+				if _, ok := conversions[other]; ok {
+					continue
+				}
+				conversions[other] = conversion{
+					price: price,
+					route: append([]string{s.Symbol}, c.route...),
+				}
+				next = append(next, other)
+			}
+		}
+		frontier = next
+	}
+
+	for _, b := range balances {
+		if b.Balance == 0 && b.FreezeBalance == 0 {
+			continue
+		}
+		c, ok := conversions[strings.ToUpper(b.CoinType)]
+		if !ok {
+			portfolio.Unvalued = append(portfolio.Unvalued, b)
+			continue
+		}
+		item := PortfolioItem{
+			CoinBalance: b,
+			Price:       c.price,
+			FreeValue:   b.Balance * c.price,
+			FrozenValue: b.FreezeBalance * c.price,
+			Route:       c.route,
+		}
+		item.Value = item.FreeValue + item.FrozenValue
+		portfolio.Items = append(portfolio.Items, item)
+		portfolio.Free += item.FreeValue
+		portfolio.Frozen += item.FrozenValue
+	}
+	portfolio.Total = portfolio.Free + portfolio.Frozen
+	sort.SliceStable(portfolio.Items, func(i, j int) bool {
+		return portfolio.Items[i].Value > portfolio.Items[j].Value
+	})
+	return
+}
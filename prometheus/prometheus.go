@@ -0,0 +1,182 @@
+// Package prometheus implements kucoin.Metrics with the counters, gauges and
+// histograms of the Prometheus client library. A Collector is registered like
+// any other, here with prom the client library's prometheus package, or
+// serves its metrics itself:
+//
+//	m := prometheus.New("kucoin")
+//	k := kucoin.New("API_KEY", "API_SECRET", kucoin.WithMetrics(m))
+//	ws.SetMetrics(m)
+//	prom.MustRegister(m)
+//	// or
+//	http.Handle("/metrics", m)
+package prometheus
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fiore/kucoin-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/expfmt"
+)
+
+// DefaultBuckets are the upper bounds in seconds of the duration histograms.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Collector collects the measurements of Kucoin clients. It implements
+// kucoin.Metrics, prometheus.Collector and http.Handler, serving the
+// metrics to be scraped.
+type Collector struct {
+	collectors []prometheus.Collector
+	registry   *prometheus.Registry
+	handler    http.Handler
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	authFailures    *prometheus.CounterVec
+	retries         *prometheus.CounterVec
+	rateLimitWaits  *prometheus.CounterVec
+	rateLimitWait   *prometheus.CounterVec
+	messages        *prometheus.CounterVec
+	decodeErrors    *prometheus.CounterVec
+	resubscriptions *prometheus.CounterVec
+	ping            prometheus.Histogram
+	backlog         *prometheus.GaugeVec
+}
+
+var (
+	_ kucoin.Metrics       = (*Collector)(nil)
+	_ prometheus.Collector = (*Collector)(nil)
+)
+
+// New returns a Collector of metrics named with the namespace, e.g.
+// kucoin_requests_total for the namespace kucoin.
+func New(namespace string) *Collector {
+	c := &Collector{}
+	counter := func(name, help string, labels ...string) *prometheus.CounterVec {
+		v := prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: namespace, Name: name, Help: help}, labels)
+		c.collectors = append(c.collectors, v)
+		return v
+	}
+	c.requests = counter("requests_total", "REST requests by endpoint and HTTP status.", "method", "endpoint", "status")
+	c.requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "request_duration_seconds",
+		Help:      "Duration of the REST requests.",
+		Buckets:   DefaultBuckets,
+	}, []string{"method", "endpoint"})
+	c.collectors = append(c.collectors, c.requestDuration)
+	c.authFailures = counter("auth_failures_total", "REST requests which couldn't be signed or were unauthorized.", "endpoint")
+	c.retries = counter("retries_total", "Retried REST requests.", "endpoint")
+	c.rateLimitWaits = counter("rate_limit_waits_total", "REST requests delayed by the rate limit.", "endpoint")
+	c.rateLimitWait = counter("rate_limit_wait_seconds_total", "Time REST requests waited for the rate limit.", "endpoint")
+	c.messages = counter("ws_messages_total", "Websocket data frames by topic.", "topic")
+	c.decodeErrors = counter("ws_decode_errors_total", "Websocket frames which failed to decode.", "topic")
+	c.resubscriptions = counter("ws_resubscriptions_total", "Topics and symbols subscribed again by the same WebSocket.", "topic")
+	c.ping = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ws_ping_rtt_seconds",
+		Help:      "Round-trip time of websocket pings.",
+		Buckets:   DefaultBuckets,
+	})
+	c.backlog = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ws_backlog",
+		Help:      "Updates waiting in the websocket updates channels.",
+	}, []string{"topic"})
+	c.collectors = append(c.collectors, c.ping, c.backlog)
+
+	c.registry = prometheus.NewPedanticRegistry()
+	c.registry.MustRegister(c)
+	c.handler = promhttp.HandlerFor(c.registry, promhttp.HandlerOpts{})
+	return c
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, m := range c.collectors {
+		m.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, m := range c.collectors {
+		m.Collect(ch)
+	}
+}
+
+// Request implements kucoin.Metrics.
+func (c *Collector) Request(method, endpoint string, status int, d time.Duration) {
+	c.requests.WithLabelValues(method, endpoint, strconv.Itoa(status)).Inc()
+	c.requestDuration.WithLabelValues(method, endpoint).Observe(d.Seconds())
+}
+
+// AuthFailure implements kucoin.Metrics.
+func (c *Collector) AuthFailure(endpoint string) {
+	c.authFailures.WithLabelValues(endpoint).Inc()
+}
+
+// Retry implements kucoin.Metrics.
+func (c *Collector) Retry(endpoint string) {
+	c.retries.WithLabelValues(endpoint).Inc()
+}
+
+// RateLimitWait implements kucoin.Metrics.
+func (c *Collector) RateLimitWait(endpoint string, d time.Duration) {
+	c.rateLimitWaits.WithLabelValues(endpoint).Inc()
+	c.rateLimitWait.WithLabelValues(endpoint).Add(d.Seconds())
+}
+
+// Message implements kucoin.Metrics.
+func (c *Collector) Message(topic string) {
+	c.messages.WithLabelValues(topic).Inc()
+}
+
+// DecodeError implements kucoin.Metrics.
+func (c *Collector) DecodeError(topic string) {
+	c.decodeErrors.WithLabelValues(topic).Inc()
+}
+
+// Resubscribe implements kucoin.Metrics.
+func (c *Collector) Resubscribe(topic string) {
+	c.resubscriptions.WithLabelValues(topic).Inc()
+}
+
+// Ping implements kucoin.Metrics.
+func (c *Collector) Ping(rtt time.Duration) {
+	c.ping.Observe(rtt.Seconds())
+}
+
+// Backlog implements kucoin.Metrics.
+func (c *Collector) Backlog(topic string, n int) {
+	c.backlog.WithLabelValues(topic).Set(float64(n))
+}
+
+// ServeHTTP serves the metrics of the Collector alone, in the format
+// negotiated with the scraper.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.handler.ServeHTTP(w, r)
+}
+
+// WriteTo writes the metrics in the Prometheus text format.
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	families, err := c.registry.Gather()
+	if err != nil {
+		return 0, err
+	}
+	bw := bufio.NewWriter(w)
+	var n int64
+	for _, f := range families {
+		m, err := expfmt.MetricFamilyToText(bw, f)
+		n += int64(m)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, bw.Flush()
+}
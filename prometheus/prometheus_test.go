@@ -0,0 +1,80 @@
+package prometheus_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fiore/kucoin-go/prometheus"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollector(t *testing.T) {
+	c := prometheus.New("kucoin")
+	c.Request("GET", "account/:coin/balance", 200, 30*time.Millisecond)
+	c.Request("GET", "account/:coin/balance", 200, 2*time.Second)
+	c.Request("POST", "order", 401, time.Millisecond)
+	c.AuthFailure("order")
+	c.RateLimitWait("order", 1500*time.Millisecond)
+	c.Message("THistory")
+	c.Message("THistory")
+	c.DecodeError("TOrderBook")
+	c.Resubscribe("THistory")
+	c.Ping(20 * time.Millisecond)
+	c.Backlog("THistory", 3)
+	c.Backlog("THistory", 1)
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	require.True(t, strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4"))
+	out := rec.Body.String()
+	for _, line := range []string{
+		"# TYPE kucoin_requests_total counter",
+		`kucoin_requests_total{endpoint="account/:coin/balance",method="GET",status="200"} 2`,
+		`kucoin_requests_total{endpoint="order",method="POST",status="401"} 1`,
+		"# TYPE kucoin_request_duration_seconds histogram",
+		`kucoin_request_duration_seconds_bucket{endpoint="account/:coin/balance",method="GET",le="0.025"} 0`,
+		`kucoin_request_duration_seconds_bucket{endpoint="account/:coin/balance",method="GET",le="0.05"} 1`,
+		`kucoin_request_duration_seconds_bucket{endpoint="account/:coin/balance",method="GET",le="2.5"} 2`,
+		`kucoin_request_duration_seconds_bucket{endpoint="account/:coin/balance",method="GET",le="+Inf"} 2`,
+		`kucoin_request_duration_seconds_sum{endpoint="account/:coin/balance",method="GET"} 2.03`,
+		`kucoin_request_duration_seconds_count{endpoint="account/:coin/balance",method="GET"} 2`,
+		`kucoin_auth_failures_total{endpoint="order"} 1`,
+		`kucoin_rate_limit_waits_total{endpoint="order"} 1`,
+		`kucoin_rate_limit_wait_seconds_total{endpoint="order"} 1.5`,
+		`kucoin_ws_messages_total{topic="THistory"} 2`,
+		`kucoin_ws_decode_errors_total{topic="TOrderBook"} 1`,
+		`kucoin_ws_resubscriptions_total{topic="THistory"} 1`,
+		`kucoin_ws_ping_rtt_seconds_bucket{le="0.025"} 1`,
+		`kucoin_ws_ping_rtt_seconds_count 1`,
+		"# TYPE kucoin_ws_backlog gauge",
+		`kucoin_ws_backlog{topic="THistory"} 1`,
+	} {
+		require.Contains(t, out, line+"\n")
+	}
+	// Families without series aren't written.
+	require.False(t, strings.Contains(out, "retries_total"))
+
+	var b strings.Builder
+	n, err := c.WriteTo(&b)
+	require.NoError(t, err)
+	require.Equal(t, int64(b.Len()), n)
+	require.Equal(t, out, b.String())
+
+	// The collector can be registered with the client library, also next
+	// to another client's collector of another namespace.
+	reg := prom.NewPedanticRegistry()
+	require.NoError(t, reg.Register(c))
+	require.NoError(t, reg.Register(prometheus.New("hedge")))
+	families, err := reg.Gather()
+	require.NoError(t, err)
+	var names []string
+	for _, f := range families {
+		names = append(names, f.GetName())
+	}
+	require.Contains(t, names, "kucoin_requests_total")
+	require.Contains(t, names, "hedge_ws_ping_rtt_seconds")
+	require.Error(t, reg.Register(prometheus.New("kucoin")))
+}
@@ -0,0 +1,184 @@
+// Package recorder records websocket frames into compressed segment files
+// and replays them through the websocket decoder.
+//
+// Record a connection:
+//
+//	rec, err := recorder.New("data", recorder.Options{})
+//	...
+//	c.SetRaw(rec.Record)
+//
+// Segments are gzipped JSON Lines of websocket.RawFrame, named
+// PREFIX-TIME.jsonl.gz so that they sort in recording order.
+package recorder
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fiore/kucoin-go/websocket"
+)
+
+const (
+	// DefaultPrefix is the default segment file name prefix.
+	DefaultPrefix = "frames"
+	// DefaultMaxSize is the default uncompressed size of a segment.
+	DefaultMaxSize = 64 << 20
+	// DefaultMaxAge is the default time a segment is written to.
+	DefaultMaxAge = time.Hour
+
+	segmentExt  = ".jsonl.gz"
+	segmentTime = "20060102T150405.000000000Z"
+)
+
+// ErrClosed is returned when recording to a closed Recorder.
+var ErrClosed = errors.New("Recorder is closed")
+
+// Options configures a Recorder. Zero values use the defaults.
+type Options struct {
+	Prefix  string
+	MaxSize int64         // uncompressed bytes, then a new segment is started
+	MaxAge  time.Duration // age of a segment, then a new segment is started
+}
+
+// Recorder writes frames into rotating segment files. It is safe for
+// concurrent use, so that one Recorder can record several connections.
+type Recorder struct {
+	dir  string
+	opts Options
+
+	mu     sync.Mutex
+	f      *os.File
+	gz     *gzip.Writer
+	size   int64
+	opened time.Time
+	closed bool
+	err    error
+}
+
+// New returns a Recorder writing segments into dir, created if needed.
+func New(dir string, opts Options) (*Recorder, error) {
+	if len(opts.Prefix) < 1 {
+		opts.Prefix = DefaultPrefix
+	}
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = DefaultMaxSize
+	}
+	if opts.MaxAge <= 0 {
+		opts.MaxAge = DefaultMaxAge
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Recorder{dir: dir, opts: opts}, nil
+}
+
+// Record writes the frame. It has the signature of websocket.Conn.SetRaw
+// handlers: failures are kept and returned by Err.
+func (r *Recorder) Record(fr websocket.RawFrame) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	r.err = r.write(fr)
+}
+
+func (r *Recorder) write(fr websocket.RawFrame) error {
+	if r.closed {
+		return ErrClosed
+	}
+	b, err := json.Marshal(fr)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if r.gz != nil && (r.size+int64(len(b)) > r.opts.MaxSize || time.Since(r.opened) >= r.opts.MaxAge) {
+		if err = r.closeSegment(); err != nil {
+			return err
+		}
+	}
+	if r.gz == nil {
+		if err = r.openSegment(); err != nil {
+			return err
+		}
+	}
+	n, err := r.gz.Write(b)
+	r.size += int64(n)
+	return err
+}
+
+func (r *Recorder) openSegment() error {
+	now := time.Now()
+	name := fmt.Sprintf("%s-%s%s", r.opts.Prefix, now.UTC().Format(segmentTime), segmentExt)
+	f, err := os.OpenFile(filepath.Join(r.dir, name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	r.f, r.gz, r.size, r.opened = f, gzip.NewWriter(f), 0, now
+	return nil
+}
+
+func (r *Recorder) closeSegment() error {
+	if r.gz == nil {
+		return nil
+	}
+	err := r.gz.Close()
+	if err2 := r.f.Close(); err == nil {
+		err = err2
+	}
+	r.f, r.gz = nil, nil
+	return err
+}
+
+// Flush writes the buffered frames to the current segment, so that it can
+// be replayed while recording.
+func (r *Recorder) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == nil && r.gz != nil {
+		r.err = r.gz.Flush()
+	}
+	return r.err
+}
+
+// Err returns the first error met while recording.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Close closes the current segment. Frames recorded after Close are dropped.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return r.err
+	}
+	r.closed = true
+	if err := r.closeSegment(); r.err == nil {
+		r.err = err
+	}
+	return r.err
+}
+
+// Segments returns the segments in dir with the prefix, in recording order.
+// An empty prefix means DefaultPrefix.
+func Segments(dir, prefix string) ([]string, error) {
+	if len(prefix) < 1 {
+		prefix = DefaultPrefix
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, prefix+"-*"+segmentExt))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
@@ -0,0 +1,99 @@
+package recorder_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	kucoinGo "github.com/fiore/kucoin-go"
+	"github.com/fiore/kucoin-go/recorder"
+	"github.com/fiore/kucoin-go/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+func frame(tc websocket.Topic, sym string, seq int, at time.Time) websocket.RawFrame {
+	return websocket.RawFrame{
+		Topic:     tc,
+		Symbol:    sym,
+		Seq:       uint64(seq),
+		Timestamp: kucoinGo.NewMillis(at),
+		Received:  kucoinGo.NewMillis(at),
+		Payload: []byte(fmt.Sprintf(`{"type":"message","seq":%d,"timestamp":%d,"data":{"oid":"%d","price":1.5,"count":%d,"direction":"BUY","time":%d}}`,
+			seq, at.UnixNano()/1e6, seq, seq, at.UnixNano()/1e6)),
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	dir := t.TempDir()
+	rec, err := recorder.New(dir, recorder.Options{MaxSize: 512})
+	require.NoError(t, err)
+
+	at := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	rec.Record(websocket.RawFrame{Topic: websocket.THistory, Symbol: "KCS-BTC", Received: kucoinGo.NewMillis(at), Payload: []byte(`{"type":"ack"}`)})
+	for i := 1; i <= 20; i++ {
+		rec.Record(frame(websocket.THistory, "KCS-BTC", i, at.Add(time.Duration(i)*time.Millisecond)))
+		rec.Record(frame(websocket.THistory, "ETH-BTC", i, at.Add(time.Duration(i)*time.Millisecond)))
+		// Segment names have nanoseconds, make sure they differ.
+		time.Sleep(time.Microsecond)
+	}
+	require.NoError(t, rec.Close())
+	rec.Record(frame(websocket.THistory, "KCS-BTC", 21, at))
+	require.Equal(t, recorder.ErrClosed, rec.Err())
+
+	paths, err := recorder.Segments(dir, "")
+	require.NoError(t, err)
+	require.True(t, len(paths) > 1, "segments should rotate")
+
+	r := recorder.NewReplayer(0, paths...)
+	var src websocket.Source = r.Subscribe(websocket.THistory, "KCS-BTC")
+	other := r.Subscribe(websocket.TOrderBook, "KCS-BTC")
+	errc := make(chan error, 1)
+	go func() { errc <- r.Run(context.Background()) }()
+
+	var n int
+	for up := range src.Updates() {
+		n++
+		h, ok := up.(*websocket.History)
+		require.True(t, ok, "%v", up)
+		require.Equal(t, "KCS-BTC", h.Symbol)
+		require.Equal(t, fmt.Sprint(n), h.Id)
+		require.Equal(t, float64(n), h.Count)
+		require.Equal(t, kucoinGo.Buy, h.Direction)
+	}
+	require.Equal(t, 20, n)
+	require.NoError(t, <-errc)
+	_, ok := <-other.Updates()
+	require.False(t, ok)
+}
+
+func TestReplaySpeed(t *testing.T) {
+	dir := t.TempDir()
+	rec, err := recorder.New(dir, recorder.Options{})
+	require.NoError(t, err)
+	at := time.Now()
+	rec.Record(frame(websocket.THistory, "KCS-BTC", 1, at))
+	rec.Record(frame(websocket.THistory, "KCS-BTC", 2, at.Add(time.Second)))
+	// A segment being recorded is replayed up to the last flush.
+	require.NoError(t, rec.Flush())
+	defer rec.Close()
+
+	paths, err := recorder.Segments(dir, "")
+	require.NoError(t, err)
+	r := recorder.NewReplayer(10, paths...)
+	s := r.Subscribe(websocket.THistory, "KCS-BTC")
+	start := time.Now()
+	go r.Run(context.Background())
+	var n int
+	for range s.Updates() {
+		n++
+	}
+	require.Equal(t, 2, n)
+	require.InDelta(t, 100*time.Millisecond, time.Since(start), float64(80*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r = recorder.NewReplayer(0, paths...)
+	r.Subscribe(websocket.THistory, "KCS-BTC")
+	require.Equal(t, context.Canceled, r.Run(ctx))
+}
@@ -0,0 +1,173 @@
+package recorder
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/fiore/kucoin-go/websocket"
+)
+
+// Stream receives the replayed updates of a topic and symbol. It is a
+// websocket.Source, so that code reading a websocket.Conn can read it.
+type Stream struct {
+	tc   websocket.Topic
+	sym  string
+	up   chan interface{}
+	done chan struct{}
+	once sync.Once
+}
+
+// Symbol returns the symbol of the stream.
+func (s *Stream) Symbol() string {
+	return s.sym
+}
+
+// Updates returns the updates, decoded as by websocket.Conn. The channel is
+// closed at the end of the replay.
+func (s *Stream) Updates() <-chan interface{} {
+	return s.up
+}
+
+// Close stops sending updates to the stream.
+func (s *Stream) Close() error {
+	s.once.Do(func() { close(s.done) })
+	return nil
+}
+
+// Replayer feeds recorded segments back through the websocket decoder.
+type Replayer struct {
+	paths []string
+	speed float64
+
+	mu      sync.Mutex
+	streams []*Stream
+}
+
+// NewReplayer returns a Replayer of the segments, read in the given order.
+// Speed 1 replays at the recorded pace, 10 ten times faster, and 0 as fast
+// as the streams are read.
+func NewReplayer(speed float64, paths ...string) *Replayer {
+	return &Replayer{paths: paths, speed: speed}
+}
+
+// Subscribe returns a stream of the recorded frames of the topic and symbol.
+// Subscribe before calling Run.
+func (r *Replayer) Subscribe(tc websocket.Topic, sym string) *Stream {
+	s := &Stream{
+		tc:   tc,
+		sym:  sym,
+		up:   make(chan interface{}, 10),
+		done: make(chan struct{}),
+	}
+	r.mu.Lock()
+	r.streams = append(r.streams, s)
+	r.mu.Unlock()
+	return s
+}
+
+// Run replays the segments and closes the streams when done. A truncated
+// last frame, as left by a segment still being recorded, ends its segment.
+func (r *Replayer) Run(ctx context.Context) error {
+	r.mu.Lock()
+	streams := r.streams
+	r.mu.Unlock()
+	defer func() {
+		for _, s := range streams {
+			close(s.up)
+		}
+	}()
+
+	var first, start time.Time
+	for _, path := range r.paths {
+		err := readSegment(path, func(fr websocket.RawFrame) error {
+			if r.speed > 0 {
+				if first.IsZero() {
+					first, start = fr.Received.Time, time.Now()
+				}
+				at := start.Add(time.Duration(float64(fr.Received.Sub(first)) / r.speed))
+				if err := sleep(ctx, time.Until(at)); err != nil {
+					return err
+				}
+			}
+			var res interface{}
+			for _, s := range streams {
+				if s.tc != fr.Topic || s.sym != fr.Symbol {
+					continue
+				}
+				if res == nil {
+					// Every stream needs its own update.
+					if res = websocket.Decode(fr.Topic, fr.Symbol, fr.Payload); res == nil {
+						return nil
+					}
+				}
+				select {
+				case s.up <- res:
+				case <-s.done:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+				res = nil
+			}
+			return ctx.Err()
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readSegment(path string, fn func(websocket.RawFrame) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		if err == io.EOF {
+			// Nothing was flushed yet.
+			return nil
+		}
+		return err
+	}
+	defer gz.Close()
+
+	br := bufio.NewReader(gz)
+	for {
+		line, err := br.ReadBytes('\n')
+		if err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil
+			}
+			return err
+		}
+		var fr websocket.RawFrame
+		if err = json.Unmarshal(line, &fr); err != nil {
+			return err
+		}
+		if err = fn(fr); err != nil {
+			return err
+		}
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
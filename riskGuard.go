@@ -0,0 +1,406 @@
+package kucoin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrKillSwitch is returned for every order while the kill switch is engaged.
+var ErrKillSwitch = errors.New("Kill switch is engaged")
+
+// RiskRule names a pre-trade check of RiskGuard.
+type RiskRule string
+
+// Pre-trade checks of RiskGuard.
+const (
+	RuleOrderNotional  RiskRule = "order notional"
+	RuleSymbolNotional RiskRule = "symbol notional"
+	RuleOpenOrders     RiskRule = "open orders"
+	RulePriceBand      RiskRule = "price band"
+	RuleBalance        RiskRule = "balance"
+)
+
+// RiskError is returned when an order violates one of the RiskLimits.
+type RiskError struct {
+	Rule  RiskRule
+	Limit float64
+	Value float64
+}
+
+func (e *RiskError) Error() string {
+	return fmt.Sprintf("Risk limit '%s' violated: %v exceeds %v", e.Rule, e.Value, e.Limit)
+}
+
+// RiskLimits holds the limits enforced by RiskGuard. Zero values disable a check.
+type RiskLimits struct {
+	// MaxOrderNotional is the max price * amount of a single order, in pair coin.
+	MaxOrderNotional float64
+	// MaxSymbolNotional is the max notional of active orders of a symbol
+	// including the new one, in pair coin.
+	MaxSymbolNotional float64
+	// MaxOpenOrders is the max number of active orders of a symbol
+	// including the new one.
+	MaxOpenOrders int
+	// MaxPriceDeviation is the max relative distance of the order price
+	// from Symbol.LastDealPrice, e.g. 0.05 for 5%.
+	MaxPriceDeviation float64
+	// CheckBalance rejects orders which exceed the available balance.
+	CheckBalance bool
+	// PriceMaxAge is how long the last deal price of a symbol is reused by
+	// the price band check. Defaults to 5 seconds.
+	PriceMaxAge time.Duration
+}
+
+// defaultPriceMaxAge is the default RiskLimits.PriceMaxAge.
+const defaultPriceMaxAge = 5 * time.Second
+
+// RiskGuard enforces pre-trade risk checks in front of CreateOrder,
+// CreateOrderByString and CreateOrderWithOptions. Orders violating the limits
+// are rejected with *RiskError and never sent to Kucoin. It only exposes the
+// Trader methods, the read and cancel ones being passed through, so that
+// orders can't bypass the checks.
+type RiskGuard struct {
+	k      *Kucoin
+	limits RiskLimits
+	state  *riskState
+}
+
+// riskState is shared by a guard and its copies returned by WithContext.
+type riskState struct {
+	mu      sync.Mutex
+	killed  bool
+	symbols map[string]struct{}
+	locks   map[string]*sync.Mutex
+	prices  map[string]lastPrice
+}
+
+type lastPrice struct {
+	price float64
+	at    time.Time
+}
+
+// lock locks the keys, sorted to avoid deadlocks, and returns their unlock.
+func (s *riskState) lock(keys ...string) (unlock func()) {
+	sort.Strings(keys)
+	s.mu.Lock()
+	mus := make([]*sync.Mutex, len(keys))
+	for i, key := range keys {
+		if s.locks[key] == nil {
+			s.locks[key] = new(sync.Mutex)
+		}
+		mus[i] = s.locks[key]
+	}
+	s.mu.Unlock()
+	for _, mu := range mus {
+		mu.Lock()
+	}
+	return func() {
+		for i := len(mus) - 1; i >= 0; i-- {
+			mus[i].Unlock()
+		}
+	}
+}
+
+// NewRiskGuard returns a RiskGuard enforcing limits in front of k.
+func NewRiskGuard(k *Kucoin, limits RiskLimits) *RiskGuard {
+	return &RiskGuard{
+		k:      k,
+		limits: limits,
+		state: &riskState{
+			symbols: make(map[string]struct{}),
+			locks:   make(map[string]*sync.Mutex),
+			prices:  make(map[string]lastPrice),
+		},
+	}
+}
+
+// WithContext returns a copy of g whose requests use ctx, sharing the limits
+// and the kill switch of g.
+func (g *RiskGuard) WithContext(ctx context.Context) *RiskGuard {
+	g2 := *g
+	g2.k = g.k.WithContext(ctx)
+	return &g2
+}
+
+// GetSymbol is passed through to Kucoin.
+func (g *RiskGuard) GetSymbol(symbol string) (Symbol, error) {
+	return g.k.GetSymbol(symbol)
+}
+
+// OrdersBook is passed through to Kucoin.
+func (g *RiskGuard) OrdersBook(symbol string, group, limit int, direction Side) (OrdersBook, error) {
+	return g.k.OrdersBook(symbol, group, limit, direction)
+}
+
+// GetCoinBalance is passed through to Kucoin.
+func (g *RiskGuard) GetCoinBalance(coin string) (CoinBalance, error) {
+	return g.k.GetCoinBalance(coin)
+}
+
+// CancelOrder is passed through to Kucoin.
+func (g *RiskGuard) CancelOrder(symbol, orderOid string, side Side) error {
+	return g.k.CancelOrder(symbol, orderOid, side)
+}
+
+// CancelAllOrders is passed through to Kucoin.
+func (g *RiskGuard) CancelAllOrders(symbol string, side Side) error {
+	return g.k.CancelAllOrders(symbol, side)
+}
+
+// ListActiveMapOrders is passed through to Kucoin.
+func (g *RiskGuard) ListActiveMapOrders(symbol string, side Side) (ActiveMapOrder, error) {
+	return g.k.ListActiveMapOrders(symbol, side)
+}
+
+// OrderDetails is passed through to Kucoin.
+func (g *RiskGuard) OrderDetails(symbol string, side Side, orderOid string, limit, page int) (OrderDetails, error) {
+	return g.k.OrderDetails(symbol, side, orderOid, limit, page)
+}
+
+// ListMergedDealtOrders is passed through to Kucoin.
+func (g *RiskGuard) ListMergedDealtOrders(symbol string, side Side, limit, page int, since, before time.Time) (MergedDealtOrder, error) {
+	return g.k.ListMergedDealtOrders(symbol, side, limit, page, since, before)
+}
+
+// CreateOrder is used to create order at Kucoin once it passes the risk checks.
+func (g *RiskGuard) CreateOrder(symbol string, side Side, price, amount float64) (orderOid string, err error) {
+	return g.place(symbol, side, price, amount, func() (string, error) {
+		return g.k.CreateOrder(symbol, side, price, amount)
+	})
+}
+
+// CreateOrderByString is used to create order at Kucoin once it passes the risk checks.
+func (g *RiskGuard) CreateOrderByString(symbol string, side Side, price, amount string) (orderOid string, err error) {
+	if len(symbol) < 1 || len(side) < 1 || len(price) < 1 || len(amount) < 1 {
+		return orderOid, ErrAllParamsRequired
+	}
+	p, err := strconv.ParseFloat(price, 64)
+	if err != nil {
+		return
+	}
+	a, err := strconv.ParseFloat(amount, 64)
+	if err != nil {
+		return
+	}
+	return g.place(symbol, side, p, a, func() (string, error) {
+		return g.k.CreateOrderByString(symbol, side, price, amount)
+	})
+}
+
+// CreateOrderWithOptions is used to create order with options at Kucoin once it passes the risk checks.
+func (g *RiskGuard) CreateOrderWithOptions(symbol string, side Side, price, amount float64, opts OrderOptions) (orderOid string, err error) {
+	return g.place(symbol, side, price, amount, func() (string, error) {
+		return g.k.CreateOrderWithOptions(symbol, side, price, amount, opts)
+	})
+}
+
+// place checks the order then creates it, holding the lock of the symbol
+// and, if the balance is checked, of the spent coin: concurrent orders are
+// checked against each other instead of the same active orders and balance.
+func (g *RiskGuard) place(symbol string, side Side, price, amount float64, create func() (string, error)) (string, error) {
+	keys := []string{"symbol:" + strings.ToUpper(symbol)}
+	if coin, _, ok := spentCoin(symbol, side, price, amount); ok && g.limits.CheckBalance {
+		keys = append(keys, "coin:"+coin)
+	}
+	unlock := g.state.lock(keys...)
+	defer unlock()
+	if err := g.check(symbol, side, price, amount); err != nil {
+		return "", err
+	}
+	return create()
+}
+
+// spentCoin returns the coin an order spends and the spent amount.
+func spentCoin(symbol string, side Side, price, amount float64) (coin string, spent float64, ok bool) {
+	coins := strings.Split(strings.ToUpper(symbol), "-")
+	if len(coins) != 2 {
+		return "", 0, false
+	}
+	if s, _ := ParseSide(string(side)); s == Buy {
+		return coins[1], price * amount, true
+	}
+	return coins[0], amount, true
+}
+
+// lastDealPrice returns the last deal price of the symbol, reused for
+// PriceMaxAge.
+func (g *RiskGuard) lastDealPrice(symbol string) (float64, error) {
+	maxAge := g.limits.PriceMaxAge
+	if maxAge <= 0 {
+		maxAge = defaultPriceMaxAge
+	}
+	g.state.mu.Lock()
+	last, ok := g.state.prices[symbol]
+	g.state.mu.Unlock()
+	if ok && time.Since(last.at) < maxAge {
+		return last.price, nil
+	}
+	s, err := g.k.GetSymbol(symbol)
+	if err != nil {
+		return 0, err
+	}
+	g.state.mu.Lock()
+	g.state.prices[symbol] = lastPrice{s.LastDealPrice, time.Now()}
+	g.state.mu.Unlock()
+	return s.LastDealPrice, nil
+}
+
+// killConcurrency is the max number of symbols cancelled at once by Kill.
+const killConcurrency = 8
+
+// Kill engages the kill switch and cancels all orders of every symbol which
+// may hold some: the symbols traded through the guard and those whose coin
+// or pair coin has a frozen balance, which covers the orders placed without
+// the guard. Orders are rejected until Resume is called.
+func (g *RiskGuard) Kill() error {
+	g.state.mu.Lock()
+	g.state.killed = true
+	symbols := make(map[string]struct{}, len(g.state.symbols))
+	for symbol := range g.state.symbols {
+		symbols[symbol] = struct{}{}
+	}
+	g.state.mu.Unlock()
+
+	// Cancel the known symbols even if the balances can't be fetched.
+	frozen, err := g.frozenSymbols()
+	errs := []error{err}
+	for _, symbol := range frozen {
+		symbols[symbol] = struct{}{}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, killConcurrency)
+	for symbol := range symbols {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(symbol string) {
+			defer func() { <-sem; wg.Done() }()
+			if err := g.k.CancelAllOrders(symbol, ""); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("%s: %w", symbol, err))
+				mu.Unlock()
+			}
+		}(symbol)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// frozenSymbols returns the symbols whose coin or pair coin has a frozen
+// balance, i.e. which may have active orders.
+func (g *RiskGuard) frozenSymbols() ([]string, error) {
+	balances, err := g.k.GetAllBalances()
+	if err != nil {
+		return nil, err
+	}
+	frozen := make(map[string]bool)
+	for _, b := range balances {
+		if b.FreezeBalance > 0 {
+			frozen[strings.ToUpper(b.CoinType)] = true
+		}
+	}
+	if len(frozen) == 0 {
+		return nil, nil
+	}
+	var symbols []string
+	for _, cp := range g.k.getCoinsPairsList() {
+		coins := strings.Split(cp.CoinPair, "-")
+		if len(coins) == 2 && (frozen[coins[0]] || frozen[coins[1]]) {
+			symbols = append(symbols, cp.CoinPair)
+		}
+	}
+	return symbols, nil
+}
+
+// Resume disengages the kill switch.
+func (g *RiskGuard) Resume() {
+	g.state.mu.Lock()
+	g.state.killed = false
+	g.state.mu.Unlock()
+}
+
+// Killed returns if the kill switch is engaged.
+func (g *RiskGuard) Killed() bool {
+	g.state.mu.Lock()
+	defer g.state.mu.Unlock()
+	return g.state.killed
+}
+
+func (g *RiskGuard) check(symbol string, side Side, price, amount float64) error {
+	symbol = strings.ToUpper(symbol)
+	g.state.mu.Lock()
+	killed := g.state.killed
+	g.state.mu.Unlock()
+	if killed {
+		return ErrKillSwitch
+	}
+
+	// Local checks go first, then those which need market or account data.
+	notional := price * amount
+	if g.limits.MaxOrderNotional > 0 && notional > g.limits.MaxOrderNotional {
+		return &RiskError{RuleOrderNotional, g.limits.MaxOrderNotional, notional}
+	}
+
+	if g.limits.MaxPriceDeviation > 0 {
+		lastDealPrice, err := g.lastDealPrice(symbol)
+		if err != nil {
+			return err
+		}
+		if lastDealPrice > 0 {
+			deviation := math.Abs(price-lastDealPrice) / lastDealPrice
+			if deviation > g.limits.MaxPriceDeviation {
+				return &RiskError{RulePriceBand, g.limits.MaxPriceDeviation, deviation}
+			}
+		}
+	}
+
+	// Active orders and balances change with every order and fill: they are
+	// fetched for each order, not cached, under the lock of place.
+	if g.limits.MaxSymbolNotional > 0 || g.limits.MaxOpenOrders > 0 {
+		active, err := g.k.ListActiveMapOrders(symbol, "")
+		if err != nil {
+			return err
+		}
+		openOrders := len(active.BUY) + len(active.SELL) + 1
+		if g.limits.MaxOpenOrders > 0 && openOrders > g.limits.MaxOpenOrders {
+			return &RiskError{RuleOpenOrders, float64(g.limits.MaxOpenOrders), float64(openOrders)}
+		}
+		symbolNotional := notional
+		for _, o := range active.BUY {
+			symbolNotional += o.Price * o.PendingAmount
+		}
+		for _, o := range active.SELL {
+			symbolNotional += o.Price * o.PendingAmount
+		}
+		if g.limits.MaxSymbolNotional > 0 && symbolNotional > g.limits.MaxSymbolNotional {
+			return &RiskError{RuleSymbolNotional, g.limits.MaxSymbolNotional, symbolNotional}
+		}
+	}
+
+	if g.limits.CheckBalance {
+		coin, required, ok := spentCoin(symbol, side, price, amount)
+		if !ok {
+			return ErrNonExistingSymbol
+		}
+		balance, err := g.k.GetCoinBalance(coin)
+		if err != nil {
+			return err
+		}
+		if required > balance.Balance {
+			return &RiskError{RuleBalance, balance.Balance, required}
+		}
+	}
+
+	g.state.mu.Lock()
+	g.state.symbols[symbol] = struct{}{}
+	g.state.mu.Unlock()
+	return nil
+}
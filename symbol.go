@@ -14,7 +14,7 @@ type Symbol struct {
 	FeeRate       float64 `json:"feeRate"`
 	VolValue      float64 `json:"volValue"`
 	High          float64 `json:"high,omitempty"`
-	Datetime      int64   `json:"datetime"`
+	Datetime      Millis  `json:"datetime"`
 	Vol           float64 `json:"vol"`
 	Low           float64 `json:"low,omitempty"`
 	ChangeRate    float64 `json:"changeRate,omitempty"`
@@ -0,0 +1,84 @@
+package kucoin
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the name of the OpenTelemetry tracer of the package.
+const TracerName = "github.com/fiore/kucoin-go"
+
+// Span attributes set on REST calls and websocket exchanges.
+const (
+	AttrEndpoint  = attribute.Key("kucoin.endpoint")
+	AttrSymbol    = attribute.Key("kucoin.symbol")
+	AttrCoin      = attribute.Key("kucoin.coin")
+	AttrErrorCode = attribute.Key("kucoin.error_code")
+	AttrMethod    = attribute.Key("http.method")
+	AttrStatus    = attribute.Key("http.status_code")
+)
+
+// WithTracerProvider makes the client trace its requests with tp instead of
+// the global OpenTelemetry tracer provider. Spans are children of the
+// context given with Kucoin.WithContext.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *client) {
+		c.tracing = tp
+	}
+}
+
+// Tracer returns the tracer of tp, or of the global provider if tp is nil.
+func Tracer(tp trace.TracerProvider) trace.Tracer {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return tp.Tracer(TracerName)
+}
+
+func (c *client) startSpan(ctx context.Context, method, resource string, payload map[string]string) (context.Context, trace.Span) {
+	endpoint := endpointOf(resource)
+	attrs := []attribute.KeyValue{AttrMethod.String(method), AttrEndpoint.String(endpoint)}
+	if symbol := payload["symbol"]; len(symbol) > 0 {
+		attrs = append(attrs, AttrSymbol.String(symbol))
+	}
+	if parts := strings.Split(resource, "/"); endpoint != resource {
+		attrs = append(attrs, AttrCoin.String(parts[1]))
+	}
+	return Tracer(c.tracing).Start(ctx, method+" "+endpoint,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// endSpan records the outcome of a request, with the error code of the
+// Kucoin response if it failed.
+func endSpan(span trace.Span, status int, data []byte, err error) {
+	defer span.End()
+	if !span.IsRecording() {
+		return
+	}
+	if status > 0 {
+		span.SetAttributes(AttrStatus.Int(status))
+	}
+	var res struct {
+		Success *bool  `json:"success"`
+		Code    string `json:"code"`
+		Msg     string `json:"msg"`
+	}
+	if len(data) > 0 && json.Unmarshal(data, &res) == nil && res.Success != nil && !*res.Success {
+		span.SetAttributes(AttrErrorCode.String(res.Code))
+		if err == nil {
+			span.SetStatus(codes.Error, res.Msg)
+		}
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
@@ -0,0 +1,22 @@
+package kucoin
+
+// Trade struct represents a trade of the public trade tape.
+// Fields match the websocket History update.
+type Trade struct {
+	Symbol    string  `json:"-"`
+	Id        string  `json:"oid"`
+	Price     float64 `json:"price"`
+	Count     float64 `json:"count"`
+	Time      Millis  `json:"time"`
+	VolValue  float64 `json:"volValue"`
+	Direction Side    `json:"direction"`
+}
+
+// rawTrades holds trades as [time, direction, price, count, volValue, oid] arrays.
+type rawTrades struct {
+	Success   bool            `json:"success"`
+	Code      string          `json:"code"`
+	Msg       string          `json:"msg"`
+	Timestamp int64           `json:"timestamp"`
+	Data      [][]interface{} `json:"data"`
+}
@@ -0,0 +1,25 @@
+package kucoin
+
+import "time"
+
+// Trader is the set of Kucoin methods used by trading strategies:
+// orders, balances, order books and deals. Strategies written against
+// Trader can switch between live trading and a simulated exchange
+// such as papertrade by swapping the implementation.
+type Trader interface {
+	GetSymbol(symbol string) (Symbol, error)
+	OrdersBook(symbol string, group, limit int, direction Side) (OrdersBook, error)
+	GetCoinBalance(coin string) (CoinBalance, error)
+	CreateOrder(symbol string, side Side, price, amount float64) (string, error)
+	CreateOrderByString(symbol string, side Side, price, amount string) (string, error)
+	CancelOrder(symbol, orderOid string, side Side) error
+	CancelAllOrders(symbol string, side Side) error
+	ListActiveMapOrders(symbol string, side Side) (ActiveMapOrder, error)
+	OrderDetails(symbol string, side Side, orderOid string, limit, page int) (OrderDetails, error)
+	ListMergedDealtOrders(symbol string, side Side, limit, page int, since, before time.Time) (MergedDealtOrder, error)
+}
+
+var (
+	_ Trader = (*Kucoin)(nil)
+	_ Trader = (*RiskGuard)(nil)
+)
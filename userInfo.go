@@ -24,12 +24,12 @@ type UserInfo struct {
 		Last struct {
 			IP      string      `json:"ip"`
 			Context interface{} `json:"context"`
-			Time    int64       `json:"time"`
+			Time    Millis      `json:"time"`
 		} `json:"last"`
 		Current struct {
 			IP      string      `json:"ip"`
 			Context interface{} `json:"context"`
-			Time    int64       `json:"time"`
+			Time    Millis      `json:"time"`
 		} `json:"current"`
 	} `json:"loginRecord"`
 }
@@ -0,0 +1,227 @@
+package kucoin
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// defaultWalletInterval is the default WalletWatcherConfig.Interval.
+	defaultWalletInterval = time.Minute
+	// maxWalletPages is the most pages of a status fetched by a poll.
+	maxWalletPages = 10
+)
+
+var (
+	// ErrWalletWatcherStarted is returned by Run when called more than once.
+	ErrWalletWatcherStarted = errors.New("Wallet watcher already started")
+	// ErrWalletPollInProgress is returned by Poll while another poll runs.
+	ErrWalletPollInProgress = errors.New("Wallet watcher poll already in progress")
+)
+
+// WalletEvent represents a deposit or withdrawal entering a status:
+// new pending, confirmed (FINISHED) or cancelled.
+type WalletEvent struct {
+	Status WalletStatus
+	Record AccountRecord
+	// Confirmations is the number of confirmations the coin requires
+	// before a deposit is credited (Coin.ConfirmationCount).
+	Confirmations int
+}
+
+// WalletWatcherConfig holds the WalletWatcher settings.
+type WalletWatcherConfig struct {
+	// Coins holds the watched coins. Defaults to all coins of the account.
+	Coins []string
+	// Interval is the time between polls. Defaults to one minute.
+	Interval time.Duration
+	// Backfill emits events for the records found by the first poll too.
+	// Otherwise they are only remembered, so that later changes are emitted.
+	Backfill bool
+}
+
+// WalletWatcher polls AccountHistory for deposits and withdrawals in all
+// statuses and emits a WalletEvent for every new record and status change.
+// Every status is paged until a record known in the status, up to 10 pages,
+// so Interval must be short enough for them to hold all the records changed
+// in the meantime. The first poll only fetches the first pages.
+type WalletWatcher struct {
+	k       *Kucoin
+	cfg     WalletWatcherConfig
+	events  chan WalletEvent
+	started atomic.Bool
+
+	// polling is held by the running poll: the fields below are only used
+	// by it, so that no lock is held during its requests.
+	polling atomic.Bool
+	polled  bool
+	// seen holds the status of the pending records and of the final ones
+	// still on the polled pages.
+	seen          map[string]WalletStatus
+	confirmations map[string]int
+}
+
+// NewWalletWatcher returns a WalletWatcher polling k.
+func NewWalletWatcher(k *Kucoin, cfg WalletWatcherConfig) *WalletWatcher {
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultWalletInterval
+	}
+	return &WalletWatcher{
+		k:             k,
+		cfg:           cfg,
+		events:        make(chan WalletEvent, 64),
+		seen:          make(map[string]WalletStatus),
+		confirmations: make(map[string]int),
+	}
+}
+
+// Events returns the channel of the events emitted by Run.
+// It is closed once Run returns.
+func (w *WalletWatcher) Events() <-chan WalletEvent {
+	return w.events
+}
+
+// Run polls every Interval until ctx is done and sends the events to Events.
+// Failed polls are retried at the next tick; Run returns ctx.Err(). A watcher
+// runs once: further calls return ErrWalletWatcherStarted, create a new
+// watcher to restart.
+func (w *WalletWatcher) Run(ctx context.Context) error {
+	if !w.started.CompareAndSwap(false, true) {
+		return ErrWalletWatcherStarted
+	}
+	defer close(w.events)
+	ticker := time.NewTicker(w.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		events, err := w.Poll()
+		if err != nil && w.k.client.debug {
+			log.Printf("Wallet watcher poll failed: %s\n", err)
+		}
+		for _, e := range events {
+			select {
+			case w.events <- e:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Poll fetches the records once and returns the events since the last poll,
+// oldest first. It doesn't wait for a concurrent poll but returns
+// ErrWalletPollInProgress.
+func (w *WalletWatcher) Poll() (events []WalletEvent, err error) {
+	if !w.polling.CompareAndSwap(false, true) {
+		return nil, ErrWalletPollInProgress
+	}
+	defer w.polling.Store(false)
+
+	coins := w.cfg.Coins
+	if len(coins) == 0 {
+		var balances []CoinBalance
+		if balances, err = w.k.GetAllBalances(); err != nil {
+			return
+		}
+		for _, b := range balances {
+			coins = append(coins, b.CoinType)
+		}
+	}
+
+	// Fetch everything first so a failed poll leaves the state untouched.
+	var records []AccountRecord
+	for _, coin := range coins {
+		coin = strings.ToUpper(coin)
+		for _, side := range []HistoryType{Deposit, Withdraw} {
+			for _, status := range []WalletStatus{Pending, Finished, Canceled} {
+				var page []AccountRecord
+				if page, err = w.fetch(coin, side, status); err != nil {
+					return nil, err
+				}
+				records = append(records, page...)
+			}
+		}
+	}
+
+	emit := w.polled || w.cfg.Backfill
+	for _, r := range records {
+		if status, ok := w.seen[r.Oid]; ok && status == r.Status || !emit {
+			continue
+		}
+		var confirmations int
+		if confirmations, err = w.confirmationCount(r.CoinType); err != nil {
+			return nil, err
+		}
+		events = append(events, WalletEvent{Status: r.Status, Record: r, Confirmations: confirmations})
+	}
+	// Final records don't change anymore: forget them once they leave the
+	// polled pages, so that seen doesn't grow.
+	seen := make(map[string]WalletStatus, len(records))
+	for oid, status := range w.seen {
+		if status == Pending {
+			seen[oid] = status
+		}
+	}
+	for _, r := range records {
+		seen[r.Oid] = r.Status
+	}
+	w.seen = seen
+	w.polled = true
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Record.UpdatedAt.Before(events[j].Record.UpdatedAt.Time)
+	})
+	return
+}
+
+// fetch returns the records of the coin, side and status, paging until a
+// page holds a record known in the status or up to maxWalletPages pages.
+// Records moved to the next page by new ones in the meantime are only
+// returned once.
+func (w *WalletWatcher) fetch(coin string, side HistoryType, status WalletStatus) (records []AccountRecord, err error) {
+	fetched := make(map[string]bool)
+	for page := 1; page <= maxWalletPages; page++ {
+		var res AccountHistory
+		if res, err = w.k.AccountHistory(coin, side, status, page); err != nil {
+			return nil, err
+		}
+		known := !w.polled
+		for _, r := range res.Datas {
+			if len(r.CoinType) < 1 {
+				r.CoinType = coin
+			}
+			if s, ok := w.seen[r.Oid]; ok && s == r.Status {
+				known = true
+			}
+			if !fetched[r.Oid] {
+				fetched[r.Oid] = true
+				records = append(records, r)
+			}
+		}
+		if known || len(res.Datas) < 1 || res.LastPage || page >= res.PageNos {
+			break
+		}
+	}
+	return
+}
+
+func (w *WalletWatcher) confirmationCount(coin string) (int, error) {
+	coin = strings.ToUpper(coin)
+	if n, ok := w.confirmations[coin]; ok {
+		return n, nil
+	}
+	c, err := w.k.GetCoin(coin)
+	if err != nil {
+		return 0, err
+	}
+	w.confirmations[coin] = c.ConfirmationCount
+	return c.ConfirmationCount, nil
+}
@@ -1,6 +1,7 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,8 +10,50 @@ import (
 	"time"
 
 	"github.com/dgrr/fastws"
+	"github.com/fiore/kucoin-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// AttrTopic is the span attribute of websocket topics.
+const AttrTopic = attribute.Key("kucoin.topic")
+
+// RawFrame is a frame received by a Conn, before it is decoded.
+// Seq and Timestamp are set by the server on data frames.
+type RawFrame struct {
+	Topic     Topic           `json:"topic"`
+	Symbol    string          `json:"symbol"`
+	Seq       uint64          `json:"seq,omitempty"`
+	Timestamp kucoin.Millis   `json:"timestamp"`
+	Received  kucoin.Millis   `json:"received"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// newRawFrame copies payload into a RawFrame received now.
+func newRawFrame(tc Topic, sym string, payload []byte) RawFrame {
+	fr := RawFrame{
+		Topic:    tc,
+		Symbol:   sym,
+		Received: kucoin.NewMillis(time.Now()),
+		Payload:  append(json.RawMessage(nil), payload...),
+	}
+	var res wsResp
+	if json.Unmarshal(payload, &res) == nil {
+		fr.Seq, fr.Timestamp = res.Seq, kucoin.MillisOf(res.Timestamp)
+	}
+	return fr
+}
+
+// Source is a stream of updates, like a Conn or a replayed recording.
+type Source interface {
+	Symbol() string
+	Updates() <-chan interface{}
+	Close() error
+}
+
+var _ Source = (*Conn)(nil)
+
 // Conn represents WebSocket connection to a Topic.
 // Use Update() func to get server messages.
 type Conn struct {
@@ -27,6 +70,10 @@ type Conn struct {
 	sm         string
 	c          *fastws.Conn
 	lastUpdate time.Time
+	raw        atomic.Value
+	metrics    kucoin.Metrics
+	tracing    trace.TracerProvider
+	pingSent   int64 // unix nanoseconds of the last ping, accessed atomically
 }
 
 func (c *Conn) lock() {
@@ -53,6 +100,12 @@ func (c *Conn) SetUpdates(ch chan interface{}) {
 	c.unlock()
 }
 
+// SetRaw sets a function called with every frame received, before it is
+// decoded, e.g. to record the stream. It is called from the reading goroutine.
+func (c *Conn) SetRaw(fn func(RawFrame)) {
+	c.raw.Store(fn)
+}
+
 // Symbol returns current connected symbol.
 func (c *Conn) Symbol() string {
 	return c.sym
@@ -126,6 +179,24 @@ func nextID() uint64 {
 
 // Send sends actions to perform
 func (c *Conn) Send(tp Type, tc Topic, sym string) (r Response, err error) {
+	return c.SendContext(context.Background(), tp, tc, sym)
+}
+
+// SendContext sends actions to perform and waits for the ack, traced as a
+// child of ctx.
+func (c *Conn) SendContext(ctx context.Context, tp Type, tc Topic, sym string) (r Response, err error) {
+	_, span := kucoin.Tracer(c.tracing).Start(ctx, "websocket "+string(tp),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(AttrTopic.String(tc.String()), kucoin.AttrSymbol.String(sym)),
+	)
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
 	var url string
 	switch tc {
 	case TOrderBook:
@@ -160,6 +231,12 @@ func (c *Conn) Send(tp Type, tc Topic, sym string) (r Response, err error) {
 		fr, err = c.c.NextFrame() // must read ack
 		if err == nil {
 			err = json.Unmarshal(fr.Payload(), &r)
+			if span.IsRecording() && r.Type != "ack" {
+				var res wsResp
+				if json.Unmarshal(fr.Payload(), &res) == nil && len(res.Code) > 0 {
+					span.SetAttributes(kucoin.AttrErrorCode.String(res.Code.String()))
+				}
+			}
 			fastws.ReleaseFrame(fr)
 		}
 	}
@@ -183,6 +260,7 @@ func (c *Conn) checkUpdates(stop chan struct{}) {
 				if err != nil {
 					c.sendUpdate(err)
 				} else {
+					atomic.StoreInt64(&c.pingSent, time.Now().UnixNano())
 					c.c.Write(data)
 				}
 			}
@@ -217,7 +295,13 @@ func (c *Conn) handle() {
 			}
 			break
 		}
-		res := c.doDecode(c.tc, fr.Payload())
+		if fn, ok := c.raw.Load().(func(RawFrame)); ok && fn != nil {
+			fn(newRawFrame(c.tc, c.sym, fr.Payload()))
+		}
+		res, tp := c.doDecode(c.tc, fr.Payload())
+		if c.metrics != nil {
+			c.report(res, tp)
+		}
 		if c.sendUpdate(res) {
 			break
 		}
@@ -236,23 +320,55 @@ func (c *Conn) sendUpdate(res interface{}) (brk bool) {
 		}
 	}()
 	c.up <- res
+	if c.metrics != nil {
+		c.metrics.Backlog(c.tc.String(), len(c.up))
+	}
 	return
 }
 
-func (c *Conn) doDecode(tc Topic, b []byte) interface{} {
+// report reports a decoded frame of type tp to the metrics.
+func (c *Conn) report(res interface{}, tp string) {
+	switch res.(type) {
+	case nil:
+		if tp == "pong" {
+			if sent := atomic.SwapInt64(&c.pingSent, 0); sent > 0 {
+				c.metrics.Ping(time.Since(time.Unix(0, sent)))
+			}
+		}
+	case error:
+		c.metrics.DecodeError(c.tc.String())
+	default:
+		c.metrics.Message(c.tc.String())
+	}
+}
+
+// doDecode decodes a frame and returns its message type too.
+func (c *Conn) doDecode(tc Topic, b []byte) (interface{}, string) {
+	return decode(tc, c.Symbol(), b)
+}
+
+// Decode decodes a frame payload of the topic the way a Conn does, e.g. to
+// replay recorded frames. It returns *History, *OrderBook, *Market or an
+// error, and nil for acks and pongs.
+func Decode(tc Topic, sym string, b []byte) interface{} {
+	res, _ := decode(tc, sym, b)
+	return res
+}
+
+func decode(tc Topic, sym string, b []byte) (interface{}, string) {
 	var res wsResp
 	var dst interface{}
 
 	err := json.Unmarshal(b, &res)
 	if err != nil {
-		return err
+		return err, ""
 	}
 
 	switch res.Type {
 	case "ack":
-		return nil
+		return nil, res.Type
 	case "pong":
-		return nil
+		return nil, res.Type
 	}
 
 	switch res.Code.String() {
@@ -260,29 +376,29 @@ func (c *Conn) doDecode(tc Topic, b []byte) interface{} {
 		err = fmt.Errorf("%s: %s", res.Code, res.Data)
 	}
 	if err != nil {
-		return err
+		return err, res.Type
 	}
 
 	switch tc {
 	case TOrderBook:
 		dst = &OrderBook{
-			Symbol: c.Symbol(),
+			Symbol: sym,
 		}
 	case THistory:
 		dst = &History{
-			Symbol: c.Symbol(),
+			Symbol: sym,
 		}
 	case TMarket, Tick:
 		dst = new(Market)
 	default:
-		return errors.New("topic not valid")
+		return errors.New("topic not valid"), res.Type
 	}
 
 	err = json.Unmarshal(res.Data, dst)
 	if err != nil {
 		dst = err
 	}
-	return dst
+	return dst, res.Type
 }
 
 func (c *Conn) handlePingClose(fr *fastws.Frame) (err error) {
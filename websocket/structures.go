@@ -1,6 +1,10 @@
 package websocket
 
-import "encoding/json"
+import (
+	"encoding/json"
+
+	"github.com/fiore/kucoin-go"
+)
 
 type wsReq struct {
 	Id    uint64 `json:"id"`
@@ -55,42 +59,42 @@ type pingReq struct {
 
 // OrderBook is the type received from Orderbook subscription
 type OrderBook struct {
-	Symbol string  `json:"-"`
-	Volume float64 `json:"volume"`
-	Price  float64 `json:"price"`
-	Count  float64 `json:"count"`
-	Action string  `json:"action"`
-	Time   int64   `json:"time"`
-	Type   string  `json:"type"`
+	Symbol string        `json:"-"`
+	Volume float64       `json:"volume"`
+	Price  float64       `json:"price"`
+	Count  float64       `json:"count"`
+	Action string        `json:"action"`
+	Time   kucoin.Millis `json:"time"`
+	Type   kucoin.Side   `json:"type"`
 }
 
 // History is the type received from History subscription
 type History struct {
-	Symbol    string  `json:"-"`
-	Id        string  `json:"oid"`
-	Price     float64 `json:"price"`
-	Count     float64 `json:"count"`
-	Time      int64   `json:"time"`
-	VolValue  float64 `json:"volValue"`
-	Direction string  `json:"direction"`
+	Symbol    string        `json:"-"`
+	Id        string        `json:"oid"`
+	Price     float64       `json:"price"`
+	Count     float64       `json:"count"`
+	Time      kucoin.Millis `json:"time"`
+	VolValue  float64       `json:"volValue"`
+	Direction kucoin.Side   `json:"direction"`
 }
 
 // Market is the type received from Tick and Market subscription
 type Market struct {
-	CoinType      string  `json:"coinType"`
-	Trading       bool    `json:"trading"`
-	Symbol        string  `json:"symbol"`
-	LastDealPrice float64 `json:"lastDealPrice"`
-	Buy           float64 `json:"buy"`
-	Sell          float64 `json:"sell"`
-	Change        float64 `json:"change"`
-	CoinTypePair  string  `json:"coinTypePair"`
-	Sort          int     `json:"sort"`
-	FeeRate       float64 `json:"feeRate"`
-	VolValue      float64 `json:"volValue"`
-	High          float64 `json:"high"`
-	Datetime      int64   `json:"datetime"`
-	Vol           float64 `json:"vol"`
-	Low           float64 `json:"low"`
-	ChangeRate    float64 `json:"changeRate"`
+	CoinType      string        `json:"coinType"`
+	Trading       bool          `json:"trading"`
+	Symbol        string        `json:"symbol"`
+	LastDealPrice float64       `json:"lastDealPrice"`
+	Buy           float64       `json:"buy"`
+	Sell          float64       `json:"sell"`
+	Change        float64       `json:"change"`
+	CoinTypePair  string        `json:"coinTypePair"`
+	Sort          int           `json:"sort"`
+	FeeRate       float64       `json:"feeRate"`
+	VolValue      float64       `json:"volValue"`
+	High          float64       `json:"high"`
+	Datetime      kucoin.Millis `json:"datetime"`
+	Vol           float64       `json:"vol"`
+	Low           float64       `json:"low"`
+	ChangeRate    float64       `json:"changeRate"`
 }
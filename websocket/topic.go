@@ -0,0 +1,56 @@
+package websocket
+
+import (
+	"fmt"
+	"strings"
+)
+
+var topicNames = []string{
+	TOrderBook: "TOrderBook",
+	THistory:   "THistory",
+	Tick:       "Tick",
+	TMarket:    "TMarket",
+}
+
+// ParseTopic parses a topic name case-insensitively: TOrderBook, THistory,
+// Tick and TMarket, or their short forms book, history, tick and market.
+func ParseTopic(s string) (Topic, error) {
+	for tc, name := range topicNames {
+		if strings.EqualFold(s, name) || strings.EqualFold(s, shortTopicName(Topic(tc))) {
+			return Topic(tc), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid topic: %s", s)
+}
+
+func shortTopicName(tc Topic) string {
+	switch tc {
+	case TOrderBook:
+		return "book"
+	case THistory:
+		return "history"
+	case Tick:
+		return "tick"
+	case TMarket:
+		return "market"
+	}
+	return ""
+}
+
+func (tc Topic) String() string {
+	if int(tc) < len(topicNames) {
+		return topicNames[tc]
+	}
+	return fmt.Sprintf("Topic(%d)", tc)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (tc Topic) MarshalText() ([]byte, error) {
+	return []byte(tc.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (tc *Topic) UnmarshalText(b []byte) (err error) {
+	*tc, err = ParseTopic(string(b))
+	return
+}
@@ -1,13 +1,16 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"sync"
 
 	"github.com/dgrr/fastws"
+	"github.com/fiore/kucoin-go"
 	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // WebSocket represents websocket connection handler.
@@ -16,6 +19,11 @@ type WebSocket struct {
 	userType string
 	ps       []instanceServer
 	hs       []historyServer
+
+	metrics    kucoin.Metrics
+	tracing    trace.TracerProvider
+	mu         sync.Mutex
+	subscribed map[string]bool
 }
 
 // NewWS returns initilised websocket connection.
@@ -33,6 +41,17 @@ func (ws *WebSocket) SetUserType(userType string) {
 	ws.userType = userType
 }
 
+// SetMetrics makes the connections subscribed afterwards report to m.
+func (ws *WebSocket) SetMetrics(m kucoin.Metrics) {
+	ws.metrics = m
+}
+
+// SetTracerProvider makes the connections subscribed afterwards trace their
+// exchanges with tp instead of the global OpenTelemetry tracer provider.
+func (ws *WebSocket) SetTracerProvider(tp trace.TracerProvider) {
+	ws.tracing = tp
+}
+
 func (ws *WebSocket) init() error {
 	_, body, err := fasthttp.Get(nil, urlServers)
 	if err != nil {
@@ -80,20 +99,29 @@ func (ws *WebSocket) selectServers() (ps *instanceServer, hs *historyServer) {
 
 // Subscribe subscribes client to a topic.
 func (ws *WebSocket) Subscribe(tc Topic, sym string) (c *Conn, err error) {
+	return ws.SubscribeContext(context.Background(), tc, sym)
+}
+
+// SubscribeContext subscribes client to a topic, tracing the subscription
+// as a child of ctx.
+func (ws *WebSocket) SubscribeContext(ctx context.Context, tc Topic, sym string) (c *Conn, err error) {
 	var conn *fastws.Conn
 	var ps instanceServer
 	conn, ps, err = ws.dial(Subscribe, tc, sym)
 	if err == nil {
 		c = &Conn{
-			cn:  sync.NewCond(&sync.Mutex{}),
-			sym: sym,
-			ps:  ps,
-			sm:  sym,
-			tc:  tc,
-			c:   conn,
+			cn:      sync.NewCond(&sync.Mutex{}),
+			sym:     sym,
+			ps:      ps,
+			sm:      sym,
+			tc:      tc,
+			c:       conn,
+			metrics: ws.metrics,
+			tracing: ws.tracing,
 		}
-		_, err = c.Send(Subscribe, tc, sym)
+		_, err = c.SendContext(ctx, Subscribe, tc, sym)
 		if err == nil {
+			ws.subscribedTo(tc, sym)
 			c.init()
 			go c.handle()
 		} else {
@@ -104,6 +132,22 @@ func (ws *WebSocket) Subscribe(tc Topic, sym string) (c *Conn, err error) {
 	return
 }
 
+// subscribedTo reports to the metrics when the topic and symbol were
+// subscribed before by ws, e.g. to replace a lost connection: connections
+// don't reconnect by themselves.
+func (ws *WebSocket) subscribedTo(tc Topic, sym string) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	key := tc.String() + "/" + sym
+	if ws.subscribed[key] && ws.metrics != nil {
+		ws.metrics.Resubscribe(tc.String())
+	}
+	if ws.subscribed == nil {
+		ws.subscribed = make(map[string]bool)
+	}
+	ws.subscribed[key] = true
+}
+
 func (ws *WebSocket) dial(t Type, tc Topic, sym string) (c *fastws.Conn, ps instanceServer, err error) {
 	pps, _ := ws.selectServers()
 	if pps == nil {
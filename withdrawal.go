@@ -1,7 +1,15 @@
 package kucoin
 
 // Withdrawal struct represents kucoin data model.
+// Coin, Address, Amount, Fee and NetAmount are filled in from the request
+// and the coin withdrawal fees, TxOid is returned by Kucoin.
 type Withdrawal struct {
+	TxOid     string  `json:"txOid"`
+	Coin      string  `json:"coin"`
+	Address   string  `json:"address"`
+	Amount    float64 `json:"amount"`
+	Fee       float64 `json:"fee"`
+	NetAmount float64 `json:"netAmount"`
 }
 
 type rawWithdrawal struct {